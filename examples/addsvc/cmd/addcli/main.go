@@ -23,6 +23,7 @@ import (
 	thriftclient "github.com/go-kit/kit/examples/addsvc/client/thrift"
 	thriftadd "github.com/go-kit/kit/examples/addsvc/thrift/gen-go/addsvc"
 	"github.com/go-kit/kit/log"
+	thrifttransport "github.com/go-kit/kit/transport/thrift"
 )
 
 func main() {
@@ -40,6 +41,7 @@ func main() {
 		thriftProtocol   = flag.String("thrift.protocol", "binary", "binary, compact, json, simplejson")
 		thriftBufferSize = flag.Int("thrift.buffer.size", 0, "0 for unbuffered")
 		thriftFramed     = flag.Bool("thrift.framed", false, "true to enable framing")
+		thriftHeaders    = flag.Bool("thrift.headers", false, "true to exchange message headers; addsvc must agree")
 		zipkinAddr       = flag.String("zipkin.addr", "", "Enable Zipkin tracing via a Kafka Collector host:port")
 		appdashAddr      = flag.String("appdash.addr", "", "Enable Appdash tracing via an Appdash server host:port")
 		lightstepToken   = flag.String("lightstep.token", "", "Enable LightStep tracing via a LightStep access token")
@@ -128,6 +130,9 @@ func main() {
 		if *thriftFramed {
 			transportFactory = thrift.NewTFramedTransportFactory(transportFactory)
 		}
+		if *thriftHeaders {
+			protocolFactory = thrifttransport.NewHeaderProtocolFactory(protocolFactory)
+		}
 		transportSocket, err := thrift.NewTSocket(*thriftAddr)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
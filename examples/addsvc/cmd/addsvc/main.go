@@ -25,11 +25,11 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/examples/addsvc"
 	"github.com/go-kit/kit/examples/addsvc/pb"
-	thriftadd "github.com/go-kit/kit/examples/addsvc/thrift/gen-go/addsvc"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-kit/kit/tracing/opentracing"
+	thrifttransport "github.com/go-kit/kit/transport/thrift"
 )
 
 func main() {
@@ -41,6 +41,7 @@ func main() {
 		thriftProtocol   = flag.String("thrift.protocol", "binary", "binary, compact, json, simplejson")
 		thriftBufferSize = flag.Int("thrift.buffer.size", 0, "0 for unbuffered")
 		thriftFramed     = flag.Bool("thrift.framed", false, "true to enable framing")
+		thriftHeaders    = flag.Bool("thrift.headers", false, "true to exchange message headers; clients must agree")
 		zipkinAddr       = flag.String("zipkin.addr", "", "Enable Zipkin tracing via a Kafka server host:port")
 		appdashAddr      = flag.String("appdash.addr", "", "Enable Appdash tracing via an Appdash server host:port")
 		lightstepToken   = flag.String("lightstep.token", "", "Enable LightStep tracing via a LightStep access token")
@@ -72,14 +73,20 @@ func main() {
 			Help:      "Total count of characters concatenated via the Concat method.",
 		}, []string{})
 	}
+	var requests metrics.Counter
 	var duration metrics.TimeHistogram
 	{
-		// Transport level metrics.
+		// Endpoint level metrics.
+		requests = prometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: "addsvc",
+			Name:      "requests_total",
+			Help:      "Total count of requests.",
+		}, metrics.EndpointFieldKeys)
 		duration = metrics.NewTimeHistogram(time.Nanosecond, prometheus.NewSummary(stdprometheus.SummaryOpts{
 			Namespace: "addsvc",
 			Name:      "request_duration_ns",
 			Help:      "Request duration in nanoseconds.",
-		}, []string{"method", "success"}))
+		}, metrics.EndpointFieldKeys))
 	}
 
 	// Tracing domain.
@@ -132,22 +139,20 @@ func main() {
 	// Endpoint domain.
 	var sumEndpoint endpoint.Endpoint
 	{
-		sumDuration := duration.With(metrics.Field{Key: "method", Value: "Sum"})
 		sumLogger := log.NewContext(logger).With("method", "Sum")
 
 		sumEndpoint = addsvc.MakeSumEndpoint(service)
 		sumEndpoint = opentracing.TraceServer(tracer, "Sum")(sumEndpoint)
-		sumEndpoint = addsvc.EndpointInstrumentingMiddleware(sumDuration)(sumEndpoint)
+		sumEndpoint = metrics.EndpointInstrumenting("Sum", requests, duration)(sumEndpoint)
 		sumEndpoint = addsvc.EndpointLoggingMiddleware(sumLogger)(sumEndpoint)
 	}
 	var concatEndpoint endpoint.Endpoint
 	{
-		concatDuration := duration.With(metrics.Field{Key: "method", Value: "Concat"})
 		concatLogger := log.NewContext(logger).With("method", "Concat")
 
 		concatEndpoint = addsvc.MakeConcatEndpoint(service)
 		concatEndpoint = opentracing.TraceServer(tracer, "Concat")(concatEndpoint)
-		concatEndpoint = addsvc.EndpointInstrumentingMiddleware(concatDuration)(concatEndpoint)
+		concatEndpoint = metrics.EndpointInstrumenting("Concat", requests, duration)(concatEndpoint)
 		concatEndpoint = addsvc.EndpointLoggingMiddleware(concatLogger)(concatEndpoint)
 	}
 	endpoints := addsvc.Endpoints{
@@ -236,6 +241,9 @@ func main() {
 		if *thriftFramed {
 			transportFactory = thrift.NewTFramedTransportFactory(transportFactory)
 		}
+		if *thriftHeaders {
+			protocolFactory = thrifttransport.NewHeaderProtocolFactory(protocolFactory)
+		}
 
 		transport, err := thrift.NewTServerSocket(*thriftAddr)
 		if err != nil {
@@ -244,8 +252,8 @@ func main() {
 		}
 
 		logger.Log("addr", *thriftAddr)
-		errc <- thrift.NewTSimpleServer4(
-			thriftadd.NewAddServiceProcessor(addsvc.MakeThriftHandler(ctx, endpoints)),
+		errc <- thrift.NewTSimpleServerFactory4(
+			addsvc.MakeThriftProcessorFactory(ctx, endpoints, logger),
 			transport,
 			transportFactory,
 			protocolFactory,
@@ -6,14 +6,12 @@ package addsvc
 // formats. It also includes endpoint middlewares.
 
 import (
-	"fmt"
 	"time"
 
 	"golang.org/x/net/context"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/metrics"
 )
 
 // Endpoints collects all of the endpoints that compose an add service. It's
@@ -84,24 +82,6 @@ func MakeConcatEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
-// EndpointInstrumentingMiddleware returns an endpoint middleware that records
-// the duration of each invocation to the passed histogram. The middleware adds
-// a single field: "success", which is "true" if no error is returned, and
-// "false" otherwise.
-func EndpointInstrumentingMiddleware(duration metrics.TimeHistogram) endpoint.Middleware {
-	return func(next endpoint.Endpoint) endpoint.Endpoint {
-		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-
-			defer func(begin time.Time) {
-				f := metrics.Field{Key: "success", Value: fmt.Sprint(err == nil)}
-				duration.With(f).Observe(time.Since(begin))
-			}(time.Now())
-			return next(ctx, request)
-
-		}
-	}
-}
-
 // EndpointLoggingMiddleware returns an endpoint middleware that logs the
 // duration of each invocation, and the resulting error, if any.
 func EndpointLoggingMiddleware(logger log.Logger) endpoint.Middleware {
@@ -1,73 +1,172 @@
 package addsvc
 
 // This file provides server-side bindings for the Thrift transport.
+// It utilizes the transport/thrift.Server.
 //
 // This file also provides endpoint constructors that utilize a Thrift client,
-// for use in client packages, because package transport/thrift doesn't exist
-// yet. See https://github.com/go-kit/kit/issues/184.
+// for use in client packages.
 
 import (
+	"github.com/apache/thrift/lib/go/thrift"
 	"golang.org/x/net/context"
 
 	"github.com/go-kit/kit/endpoint"
 	thriftadd "github.com/go-kit/kit/examples/addsvc/thrift/gen-go/addsvc"
+	"github.com/go-kit/kit/log"
+	thrifttransport "github.com/go-kit/kit/transport/thrift"
 )
 
-// MakeThriftHandler makes a set of endpoints available as a Thrift service.
-func MakeThriftHandler(ctx context.Context, e Endpoints) thriftadd.AddService {
-	return &thriftServer{
-		ctx:    ctx,
-		sum:    e.SumEndpoint,
-		concat: e.ConcatEndpoint,
+// MakeThriftProcessorFactory makes a set of endpoints available as a Thrift
+// AddService. The returned factory constructs a processor per connection, so
+// that request headers are available to the endpoints if the protocol factory
+// is wrapped by transport/thrift.NewHeaderProtocolFactory. Headers are opt-in:
+// without the wrapper, the service speaks plain Thrift.
+func MakeThriftProcessorFactory(ctx context.Context, endpoints Endpoints, logger log.Logger) thrift.TProcessorFactory {
+	options := []thrifttransport.ServerOption{
+		thrifttransport.ServerErrorLogger(logger),
 	}
+	sum := thrifttransport.NewServer(
+		ctx,
+		endpoints.SumEndpoint,
+		DecodeThriftSumRequest,
+		EncodeThriftSumResponse,
+		options...,
+	)
+	concat := thrifttransport.NewServer(
+		ctx,
+		endpoints.ConcatEndpoint,
+		DecodeThriftConcatRequest,
+		EncodeThriftConcatResponse,
+		options...,
+	)
+	return thrifttransport.NewProcessorFactory(func(conn *thrifttransport.Conn) thrift.TProcessor {
+		return thriftadd.NewAddServiceProcessor(&thriftServer{
+			conn:   conn,
+			sum:    sum,
+			concat: concat,
+		})
+	})
 }
 
 type thriftServer struct {
-	ctx    context.Context
-	sum    endpoint.Endpoint
-	concat endpoint.Endpoint
+	conn   *thrifttransport.Conn
+	sum    thrifttransport.Handler
+	concat thrifttransport.Handler
 }
 
 func (s *thriftServer) Sum(a int64, b int64) (*thriftadd.SumReply, error) {
-	request := sumRequest{A: int(a), B: int(b)}
-	response, err := s.sum(s.ctx, request)
+	ctx := s.conn.Context(context.Background())
+	rep, err := s.sum.ServeThrift(ctx, &thriftadd.AddServiceSumArgs{A: a, B: b})
 	if err != nil {
 		return nil, err
 	}
-	resp := response.(sumResponse)
-	return &thriftadd.SumReply{Value: int64(resp.V), Err: err2str(resp.Err)}, nil
+	return rep.(*thriftadd.SumReply), nil
 }
 
 func (s *thriftServer) Concat(a string, b string) (*thriftadd.ConcatReply, error) {
-	request := concatRequest{A: a, B: b}
-	response, err := s.concat(s.ctx, request)
+	ctx := s.conn.Context(context.Background())
+	rep, err := s.concat.ServeThrift(ctx, &thriftadd.AddServiceConcatArgs{A: a, B: b})
 	if err != nil {
 		return nil, err
 	}
-	resp := response.(concatResponse)
-	return &thriftadd.ConcatReply{Value: resp.V, Err: err2str(resp.Err)}, nil
+	return rep.(*thriftadd.ConcatReply), nil
 }
 
-// MakeThriftSumEndpoint returns an endpoint that invokes the passed Thrift client.
-// Useful only in clients, and only until a proper transport/thrift.Client exists.
+// MakeThriftSumEndpoint returns an endpoint that invokes the passed Thrift
+// client. Useful only in clients. Headers are only sent if the client's
+// protocols are wrapped by transport/thrift.NewHeaderProtocolFactory.
+//
+// As with the other transports, transport errors and exceptions raised by the
+// server are returned as endpoint errors. The Err field of the response only
+// carries business errors.
 func MakeThriftSumEndpoint(client *thriftadd.AddServiceClient) endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req := request.(sumRequest)
-		reply, err := client.Sum(int64(req.A), int64(req.B))
-		if err == ErrIntOverflow {
-			return nil, err // special case; see comment on ErrIntOverflow
-		}
-		return sumResponse{V: int(reply.Value), Err: err}, nil
-	}
+	return thrifttransport.NewClient(
+		func(request interface{}) (interface{}, error) {
+			args := request.(*thriftadd.AddServiceSumArgs)
+			return client.Sum(args.A, args.B)
+		},
+		EncodeThriftSumRequest,
+		DecodeThriftSumResponse,
+		thrifttransport.ClientProtocols(client.InputProtocol, client.OutputProtocol),
+	).Endpoint()
 }
 
 // MakeThriftConcatEndpoint returns an endpoint that invokes the passed Thrift
-// client. Useful only in clients, and only until a proper
-// transport/thrift.Client exists.
+// client. Useful only in clients. Headers are only sent if the client's
+// protocols are wrapped by transport/thrift.NewHeaderProtocolFactory. Errors
+// are reported as by MakeThriftSumEndpoint.
 func MakeThriftConcatEndpoint(client *thriftadd.AddServiceClient) endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req := request.(concatRequest)
-		reply, err := client.Concat(req.A, req.B)
-		return concatResponse{V: reply.Value, Err: err}, nil
-	}
+	return thrifttransport.NewClient(
+		func(request interface{}) (interface{}, error) {
+			args := request.(*thriftadd.AddServiceConcatArgs)
+			return client.Concat(args.A, args.B)
+		},
+		EncodeThriftConcatRequest,
+		DecodeThriftConcatResponse,
+		thrifttransport.ClientProtocols(client.InputProtocol, client.OutputProtocol),
+	).Endpoint()
+}
+
+// DecodeThriftSumRequest is a transport/thrift.DecodeRequestFunc that converts
+// Thrift sum arguments to a user-domain sum request. Primarily useful in a
+// server.
+func DecodeThriftSumRequest(_ context.Context, thriftReq interface{}) (interface{}, error) {
+	req := thriftReq.(*thriftadd.AddServiceSumArgs)
+	return sumRequest{A: int(req.A), B: int(req.B)}, nil
+}
+
+// DecodeThriftConcatRequest is a transport/thrift.DecodeRequestFunc that
+// converts Thrift concat arguments to a user-domain concat request. Primarily
+// useful in a server.
+func DecodeThriftConcatRequest(_ context.Context, thriftReq interface{}) (interface{}, error) {
+	req := thriftReq.(*thriftadd.AddServiceConcatArgs)
+	return concatRequest{A: req.A, B: req.B}, nil
+}
+
+// DecodeThriftSumResponse is a transport/thrift.DecodeResponseFunc that
+// converts a Thrift sum reply to a user-domain sum response. Primarily useful
+// in a client.
+func DecodeThriftSumResponse(_ context.Context, thriftReply interface{}) (interface{}, error) {
+	reply := thriftReply.(*thriftadd.SumReply)
+	return sumResponse{V: int(reply.Value), Err: str2err(reply.Err)}, nil
+}
+
+// DecodeThriftConcatResponse is a transport/thrift.DecodeResponseFunc that
+// converts a Thrift concat reply to a user-domain concat response. Primarily
+// useful in a client.
+func DecodeThriftConcatResponse(_ context.Context, thriftReply interface{}) (interface{}, error) {
+	reply := thriftReply.(*thriftadd.ConcatReply)
+	return concatResponse{V: reply.Value, Err: str2err(reply.Err)}, nil
+}
+
+// EncodeThriftSumResponse is a transport/thrift.EncodeResponseFunc that
+// converts a user-domain sum response to a Thrift sum reply. Primarily useful
+// in a server.
+func EncodeThriftSumResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(sumResponse)
+	return &thriftadd.SumReply{Value: int64(resp.V), Err: err2str(resp.Err)}, nil
+}
+
+// EncodeThriftConcatResponse is a transport/thrift.EncodeResponseFunc that
+// converts a user-domain concat response to a Thrift concat reply. Primarily
+// useful in a server.
+func EncodeThriftConcatResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(concatResponse)
+	return &thriftadd.ConcatReply{Value: resp.V, Err: err2str(resp.Err)}, nil
+}
+
+// EncodeThriftSumRequest is a transport/thrift.EncodeRequestFunc that converts
+// a user-domain sum request to Thrift sum arguments. Primarily useful in a
+// client.
+func EncodeThriftSumRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(sumRequest)
+	return &thriftadd.AddServiceSumArgs{A: int64(req.A), B: int64(req.B)}, nil
+}
+
+// EncodeThriftConcatRequest is a transport/thrift.EncodeRequestFunc that
+// converts a user-domain concat request to Thrift concat arguments. Primarily
+// useful in a client.
+func EncodeThriftConcatRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(concatRequest)
+	return &thriftadd.AddServiceConcatArgs{A: req.A, B: req.B}, nil
 }
@@ -12,11 +12,13 @@ import (
 	"github.com/go-kit/kit/examples/shipping/cargo"
 	"github.com/go-kit/kit/examples/shipping/location"
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
 	kithttp "github.com/go-kit/kit/transport/http"
 )
 
-// MakeHandler returns a handler for the booking service.
-func MakeHandler(ctx context.Context, bs Service, logger kitlog.Logger) http.Handler {
+// MakeHandler returns a handler for the booking service. Requests to each endpoint
+// are counted, and their durations recorded, in the passed metrics.
+func MakeHandler(ctx context.Context, bs Service, requests metrics.Counter, duration metrics.TimeHistogram, logger kitlog.Logger) http.Handler {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorLogger(logger),
 		kithttp.ServerErrorEncoder(encodeError),
@@ -24,49 +26,49 @@ func MakeHandler(ctx context.Context, bs Service, logger kitlog.Logger) http.Han
 
 	bookCargoHandler := kithttp.NewServer(
 		ctx,
-		makeBookCargoEndpoint(bs),
+		metrics.EndpointInstrumenting("book", requests, duration)(makeBookCargoEndpoint(bs)),
 		decodeBookCargoRequest,
 		encodeResponse,
 		opts...,
 	)
 	loadCargoHandler := kithttp.NewServer(
 		ctx,
-		makeLoadCargoEndpoint(bs),
+		metrics.EndpointInstrumenting("load", requests, duration)(makeLoadCargoEndpoint(bs)),
 		decodeLoadCargoRequest,
 		encodeResponse,
 		opts...,
 	)
 	requestRoutesHandler := kithttp.NewServer(
 		ctx,
-		makeRequestRoutesEndpoint(bs),
+		metrics.EndpointInstrumenting("request_routes", requests, duration)(makeRequestRoutesEndpoint(bs)),
 		decodeRequestRoutesRequest,
 		encodeResponse,
 		opts...,
 	)
 	assignToRouteHandler := kithttp.NewServer(
 		ctx,
-		makeAssignToRouteEndpoint(bs),
+		metrics.EndpointInstrumenting("assign_to_route", requests, duration)(makeAssignToRouteEndpoint(bs)),
 		decodeAssignToRouteRequest,
 		encodeResponse,
 		opts...,
 	)
 	changeDestinationHandler := kithttp.NewServer(
 		ctx,
-		makeChangeDestinationEndpoint(bs),
+		metrics.EndpointInstrumenting("change_destination", requests, duration)(makeChangeDestinationEndpoint(bs)),
 		decodeChangeDestinationRequest,
 		encodeResponse,
 		opts...,
 	)
 	listCargosHandler := kithttp.NewServer(
 		ctx,
-		makeListCargosEndpoint(bs),
+		metrics.EndpointInstrumenting("list_cargos", requests, duration)(makeListCargosEndpoint(bs)),
 		decodeListCargosRequest,
 		encodeResponse,
 		opts...,
 	)
 	listLocationsHandler := kithttp.NewServer(
 		ctx,
-		makeListLocationsEndpoint(bs),
+		metrics.EndpointInstrumenting("list_locations", requests, duration)(makeListLocationsEndpoint(bs)),
 		decodeListLocationsRequest,
 		encodeResponse,
 		opts...,
@@ -1,38 +0,0 @@
-package handling
-
-import (
-	"time"
-
-	"github.com/go-kit/kit/metrics"
-
-	"github.com/go-kit/kit/examples/shipping/cargo"
-	"github.com/go-kit/kit/examples/shipping/location"
-	"github.com/go-kit/kit/examples/shipping/voyage"
-)
-
-type instrumentingService struct {
-	requestCount   metrics.Counter
-	requestLatency metrics.TimeHistogram
-	Service
-}
-
-// NewInstrumentingService returns an instance of an instrumenting Service.
-func NewInstrumentingService(requestCount metrics.Counter, requestLatency metrics.TimeHistogram, s Service) Service {
-	return &instrumentingService{
-		requestCount:   requestCount,
-		requestLatency: requestLatency,
-		Service:        s,
-	}
-}
-
-func (s *instrumentingService) RegisterHandlingEvent(completionTime time.Time, trackingID cargo.TrackingID, voyage voyage.Number,
-	loc location.UNLocode, eventType cargo.HandlingEventType) error {
-
-	defer func(begin time.Time) {
-		methodField := metrics.Field{Key: "method", Value: "register_incident"}
-		s.requestCount.With(methodField).Add(1)
-		s.requestLatency.With(methodField).Observe(time.Since(begin))
-	}(time.Now())
-
-	return s.Service.RegisterHandlingEvent(completionTime, trackingID, voyage, loc, eventType)
-}
@@ -12,11 +12,13 @@ import (
 	"github.com/go-kit/kit/examples/shipping/location"
 	"github.com/go-kit/kit/examples/shipping/voyage"
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
 	kithttp "github.com/go-kit/kit/transport/http"
 )
 
-// MakeHandler returns a handler for the handling service.
-func MakeHandler(ctx context.Context, hs Service, logger kitlog.Logger) http.Handler {
+// MakeHandler returns a handler for the handling service. Requests to each endpoint
+// are counted, and their durations recorded, in the passed metrics.
+func MakeHandler(ctx context.Context, hs Service, requests metrics.Counter, duration metrics.TimeHistogram, logger kitlog.Logger) http.Handler {
 	r := mux.NewRouter()
 
 	opts := []kithttp.ServerOption{
@@ -26,7 +28,7 @@ func MakeHandler(ctx context.Context, hs Service, logger kitlog.Logger) http.Han
 
 	registerIncidentHandler := kithttp.NewServer(
 		ctx,
-		makeRegisterIncidentEndpoint(hs),
+		metrics.EndpointInstrumenting("register_incident", requests, duration)(makeRegisterIncidentEndpoint(hs)),
 		decodeRegisterIncidentRequest,
 		encodeResponse,
 		opts...,
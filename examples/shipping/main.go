@@ -72,69 +72,28 @@ func main() {
 	// Facilitate testing by adding some cargos.
 	storeTestData(cargos)
 
-	fieldKeys := []string{"method"}
-
 	var rs routing.Service
 	rs = routing.NewProxyingMiddleware(*routingServiceURL, ctx)(rs)
 
 	var bs booking.Service
 	bs = booking.NewService(cargos, locations, handlingEvents, rs)
 	bs = booking.NewLoggingService(log.NewContext(logger).With("component", "booking"), bs)
-	bs = booking.NewInstrumentingService(
-		kitprometheus.NewCounter(stdprometheus.CounterOpts{
-			Namespace: "api",
-			Subsystem: "booking_service",
-			Name:      "request_count",
-			Help:      "Number of requests received.",
-		}, fieldKeys),
-		metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
-			Namespace: "api",
-			Subsystem: "booking_service",
-			Name:      "request_latency_microseconds",
-			Help:      "Total duration of requests in microseconds.",
-		}, fieldKeys)), bs)
 
 	var ts tracking.Service
 	ts = tracking.NewService(cargos, handlingEvents)
 	ts = tracking.NewLoggingService(log.NewContext(logger).With("component", "tracking"), ts)
-	ts = tracking.NewInstrumentingService(
-		kitprometheus.NewCounter(stdprometheus.CounterOpts{
-			Namespace: "api",
-			Subsystem: "tracking_service",
-			Name:      "request_count",
-			Help:      "Number of requests received.",
-		}, fieldKeys),
-		metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
-			Namespace: "api",
-			Subsystem: "tracking_service",
-			Name:      "request_latency_microseconds",
-			Help:      "Total duration of requests in microseconds.",
-		}, fieldKeys)), ts)
 
 	var hs handling.Service
 	hs = handling.NewService(handlingEvents, handlingEventFactory, handlingEventHandler)
 	hs = handling.NewLoggingService(log.NewContext(logger).With("component", "handling"), hs)
-	hs = handling.NewInstrumentingService(
-		kitprometheus.NewCounter(stdprometheus.CounterOpts{
-			Namespace: "api",
-			Subsystem: "handling_service",
-			Name:      "request_count",
-			Help:      "Number of requests received.",
-		}, fieldKeys),
-		metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
-			Namespace: "api",
-			Subsystem: "handling_service",
-			Name:      "request_latency_microseconds",
-			Help:      "Total duration of requests in microseconds.",
-		}, fieldKeys)), hs)
 
 	httpLogger := log.NewContext(logger).With("component", "http")
 
 	mux := http.NewServeMux()
 
-	mux.Handle("/booking/v1/", booking.MakeHandler(ctx, bs, httpLogger))
-	mux.Handle("/tracking/v1/", tracking.MakeHandler(ctx, ts, httpLogger))
-	mux.Handle("/handling/v1/", handling.MakeHandler(ctx, hs, httpLogger))
+	mux.Handle("/booking/v1/", booking.MakeHandler(ctx, bs, requestCount("booking"), requestLatency("booking"), httpLogger))
+	mux.Handle("/tracking/v1/", tracking.MakeHandler(ctx, ts, requestCount("tracking"), requestLatency("tracking"), httpLogger))
+	mux.Handle("/handling/v1/", handling.MakeHandler(ctx, hs, requestCount("handling"), requestLatency("handling"), httpLogger))
 
 	http.Handle("/", accessControl(mux))
 	http.Handle("/metrics", stdprometheus.Handler())
@@ -167,6 +126,24 @@ func accessControl(h http.Handler) http.Handler {
 	})
 }
 
+func requestCount(service string) metrics.Counter {
+	return kitprometheus.NewCounter(stdprometheus.CounterOpts{
+		Namespace: "api",
+		Subsystem: service + "_service",
+		Name:      "request_count",
+		Help:      "Number of requests received.",
+	}, metrics.EndpointFieldKeys)
+}
+
+func requestLatency(service string) metrics.TimeHistogram {
+	return metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
+		Namespace: "api",
+		Subsystem: service + "_service",
+		Name:      "request_latency_microseconds",
+		Help:      "Total duration of requests in microseconds.",
+	}, metrics.EndpointFieldKeys))
+}
+
 func envString(env, fallback string) string {
 	e := os.Getenv(env)
 	if e == "" {
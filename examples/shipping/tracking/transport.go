@@ -10,11 +10,13 @@ import (
 
 	"github.com/go-kit/kit/examples/shipping/cargo"
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
 	kithttp "github.com/go-kit/kit/transport/http"
 )
 
-// MakeHandler returns a handler for the tracking service.
-func MakeHandler(ctx context.Context, ts Service, logger kitlog.Logger) http.Handler {
+// MakeHandler returns a handler for the tracking service. Requests to each endpoint
+// are counted, and their durations recorded, in the passed metrics.
+func MakeHandler(ctx context.Context, ts Service, requests metrics.Counter, duration metrics.TimeHistogram, logger kitlog.Logger) http.Handler {
 	r := mux.NewRouter()
 
 	opts := []kithttp.ServerOption{
@@ -24,7 +26,7 @@ func MakeHandler(ctx context.Context, ts Service, logger kitlog.Logger) http.Han
 
 	trackCargoHandler := kithttp.NewServer(
 		ctx,
-		makeTrackCargoEndpoint(ts),
+		metrics.EndpointInstrumenting("track", requests, duration)(makeTrackCargoEndpoint(ts)),
 		decodeTrackCargoRequest,
 		encodeResponse,
 		opts...,
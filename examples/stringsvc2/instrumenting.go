@@ -1,39 +1,25 @@
 package main
 
 import (
-	"fmt"
-	"time"
-
 	"github.com/go-kit/kit/metrics"
 )
 
+// Requests and their latency are recorded per endpoint, in main, by
+// metrics.EndpointInstrumenting. Only the service-specific metrics remain
+// here.
 type instrumentingMiddleware struct {
-	requestCount   metrics.Counter
-	requestLatency metrics.TimeHistogram
-	countResult    metrics.Histogram
-	next           StringService
+	countResult metrics.Histogram
+	next        StringService
 }
 
-func (mw instrumentingMiddleware) Uppercase(s string) (output string, err error) {
-	defer func(begin time.Time) {
-		methodField := metrics.Field{Key: "method", Value: "uppercase"}
-		errorField := metrics.Field{Key: "error", Value: fmt.Sprintf("%v", err)}
-		mw.requestCount.With(methodField).With(errorField).Add(1)
-		mw.requestLatency.With(methodField).With(errorField).Observe(time.Since(begin))
-	}(time.Now())
-
-	output, err = mw.next.Uppercase(s)
-	return
+func (mw instrumentingMiddleware) Uppercase(s string) (string, error) {
+	return mw.next.Uppercase(s)
 }
 
 func (mw instrumentingMiddleware) Count(s string) (n int) {
-	defer func(begin time.Time) {
-		methodField := metrics.Field{Key: "method", Value: "count"}
-		errorField := metrics.Field{Key: "error", Value: fmt.Sprintf("%v", error(nil))}
-		mw.requestCount.With(methodField).With(errorField).Add(1)
-		mw.requestLatency.With(methodField).With(errorField).Observe(time.Since(begin))
+	defer func() {
 		mw.countResult.Observe(int64(n))
-	}(time.Now())
+	}()
 
 	n = mw.next.Count(s)
 	return
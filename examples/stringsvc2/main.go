@@ -18,19 +18,18 @@ func main() {
 	ctx := context.Background()
 	logger := log.NewLogfmtLogger(os.Stderr)
 
-	fieldKeys := []string{"method", "error"}
 	requestCount := kitprometheus.NewCounter(stdprometheus.CounterOpts{
 		Namespace: "my_group",
 		Subsystem: "string_service",
 		Name:      "request_count",
 		Help:      "Number of requests received.",
-	}, fieldKeys)
+	}, metrics.EndpointFieldKeys)
 	requestLatency := metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
 		Namespace: "my_group",
 		Subsystem: "string_service",
 		Name:      "request_latency_microseconds",
 		Help:      "Total duration of requests in microseconds.",
-	}, fieldKeys))
+	}, metrics.EndpointFieldKeys))
 	countResult := kitprometheus.NewSummary(stdprometheus.SummaryOpts{
 		Namespace: "my_group",
 		Subsystem: "string_service",
@@ -41,18 +40,18 @@ func main() {
 	var svc StringService
 	svc = stringService{}
 	svc = loggingMiddleware{logger, svc}
-	svc = instrumentingMiddleware{requestCount, requestLatency, countResult, svc}
+	svc = instrumentingMiddleware{countResult, svc}
 
 	uppercaseHandler := httptransport.NewServer(
 		ctx,
-		makeUppercaseEndpoint(svc),
+		metrics.EndpointInstrumenting("uppercase", requestCount, requestLatency)(makeUppercaseEndpoint(svc)),
 		decodeUppercaseRequest,
 		encodeResponse,
 	)
 
 	countHandler := httptransport.NewServer(
 		ctx,
-		makeCountEndpoint(svc),
+		metrics.EndpointInstrumenting("count", requestCount, requestLatency)(makeCountEndpoint(svc)),
 		decodeCountRequest,
 		encodeResponse,
 	)
@@ -0,0 +1,186 @@
+package levels
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Handler is an http.Handler that reports and changes the thresholds of named
+// leveled loggers at runtime. It's meant to be mounted on an administrative
+// or debug listener, e.g. at /debug/levels.
+//
+// A GET request reports the current level of every registered threshold, or
+// of a single threshold if the name parameter is given. A PUT or POST request
+// sets the threshold identified by the name parameter to the level parameter.
+// If a ttl parameter (e.g. "5m") is also given, the threshold reverts to its
+// previous level once the ttl has elapsed. Parameters may be passed in the
+// query string or as form values. Responses are JSON.
+type Handler struct {
+	mtx        sync.Mutex
+	thresholds map[string]*Threshold
+	reverts    map[string]*revert
+	logger     log.Logger
+}
+
+type revert struct {
+	level Level
+	at    time.Time
+	timer *time.Timer
+}
+
+// NewHandler returns a new, empty Handler. Changes to thresholds are logged to
+// the passed logger.
+func NewHandler(logger log.Logger) *Handler {
+	return &Handler{
+		thresholds: map[string]*Threshold{},
+		reverts:    map[string]*revert{},
+		logger:     logger,
+	}
+}
+
+// Register makes the threshold available under name. Registering a name a
+// second time replaces the previous threshold.
+func (h *Handler) Register(name string, t *Threshold) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.cancelRevert(name)
+	h.thresholds[name] = t
+}
+
+// ThresholdStatus describes the state of a named threshold. It's the JSON
+// representation returned by the Handler.
+type ThresholdStatus struct {
+	Name     string     `json:"name"`
+	Level    string     `json:"level"`
+	RevertTo string     `json:"revert_to,omitempty"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		h.get(w, r)
+	case "PUT", "POST":
+		h.set(w, r)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if name := r.FormValue("name"); name != "" {
+		if _, ok := h.thresholds[name]; !ok {
+			http.Error(w, fmt.Sprintf("unknown logger %q", name), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, h.status(name))
+		return
+	}
+
+	names := make([]string, 0, len(h.thresholds))
+	for name := range h.thresholds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	statuses := make([]ThresholdStatus, len(names))
+	for i, name := range names {
+		statuses[i] = h.status(name)
+	}
+	writeJSON(w, statuses)
+}
+
+func (h *Handler) set(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	level, err := ParseLevel(r.FormValue("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var ttl time.Duration
+	if s := r.FormValue("ttl"); s != "" {
+		if ttl, err = time.ParseDuration(s); err != nil || ttl <= 0 {
+			http.Error(w, fmt.Sprintf("invalid ttl %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	t, ok := h.thresholds[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown logger %q", name), http.StatusNotFound)
+		return
+	}
+
+	// A temporary change made on top of another temporary change still
+	// reverts to the level in effect before the first one.
+	previous := t.Level()
+	if rv, ok := h.reverts[name]; ok {
+		previous = rv.level
+	}
+	h.cancelRevert(name)
+	t.SetLevel(level)
+
+	if ttl > 0 {
+		rv := &revert{level: previous, at: time.Now().Add(ttl)}
+		rv.timer = time.AfterFunc(ttl, func() { h.revert(name, rv) })
+		h.reverts[name] = rv
+		h.logger.Log("logger", name, "level", level, "ttl", ttl, "revert_to", previous)
+	} else {
+		h.logger.Log("logger", name, "level", level)
+	}
+
+	writeJSON(w, h.status(name))
+}
+
+func (h *Handler) revert(name string, rv *revert) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	// The revert may have been superseded while waiting for the lock.
+	if h.reverts[name] != rv {
+		return
+	}
+	delete(h.reverts, name)
+	h.thresholds[name].SetLevel(rv.level)
+	h.logger.Log("logger", name, "level", rv.level, "msg", "ttl expired")
+}
+
+// cancelRevert must be called with the mutex held.
+func (h *Handler) cancelRevert(name string) {
+	if rv, ok := h.reverts[name]; ok {
+		rv.timer.Stop()
+		delete(h.reverts, name)
+	}
+}
+
+// status must be called with the mutex held.
+func (h *Handler) status(name string) ThresholdStatus {
+	s := ThresholdStatus{
+		Name:  name,
+		Level: h.thresholds[name].Level().String(),
+	}
+	if rv, ok := h.reverts[name]; ok {
+		at := rv.at
+		s.RevertTo = rv.level.String()
+		s.RevertAt = &at
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
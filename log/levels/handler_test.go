@@ -0,0 +1,129 @@
+package levels_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/levels"
+)
+
+func TestHandlerGet(t *testing.T) {
+	h := levels.NewHandler(log.NewNopLogger())
+	h.Register("b", levels.NewThreshold(levels.WarnLevel))
+	h.Register("a", levels.NewThreshold(levels.InfoLevel))
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	var statuses []levels.ThresholdStatus
+	getJSON(t, server.URL, &statuses)
+	if want, have := 2, len(statuses); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "a", statuses[0].Name; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "info", statuses[0].Level; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	var status levels.ThresholdStatus
+	getJSON(t, server.URL+"?name=b", &status)
+	if want, have := "warn", status.Level; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	resp, err := http.Get(server.URL + "?name=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusNotFound, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestHandlerSet(t *testing.T) {
+	threshold := levels.NewThreshold(levels.InfoLevel)
+	h := levels.NewHandler(log.NewNopLogger())
+	h.Register("svc", threshold)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	resp, err := http.PostForm(server.URL, url.Values{"name": {"svc"}, "level": {"debug"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusOK, resp.StatusCode; want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := levels.DebugLevel, threshold.Level(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	for _, values := range []url.Values{
+		{"name": {"svc"}, "level": {"verbose"}},
+		{"name": {"svc"}, "level": {"info"}, "ttl": {"soon"}},
+	} {
+		resp, err := http.PostForm(server.URL, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if want, have := http.StatusBadRequest, resp.StatusCode; want != have {
+			t.Errorf("%v: want %d, have %d", values, want, have)
+		}
+	}
+}
+
+func TestHandlerRevert(t *testing.T) {
+	threshold := levels.NewThreshold(levels.InfoLevel)
+	h := levels.NewHandler(log.NewNopLogger())
+	h.Register("svc", threshold)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	req, err := http.NewRequest("PUT", server.URL+"?name=svc&level=debug&ttl=50ms", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status levels.ThresholdStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := "info", status.RevertTo; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := levels.DebugLevel, threshold.Level(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for threshold.Level() != levels.InfoLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("threshold not reverted, have %s", threshold.Level())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func getJSON(t *testing.T, url string, v interface{}) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,9 +6,17 @@ import "github.com/go-kit/kit/log"
 // levels: debug, info, warning (warn), error, and critical (crit). If you
 // want a different set of levels, you can create your own levels type very
 // easily, and you can elide the configuration.
+//
+// Levels may be given a minimum level via the MinLevel or SharedThreshold
+// options, and the minimum may be changed at runtime via the Threshold method.
+// The loggers returned by the level methods consult the threshold on every
+// call to Log, so loggers retained by the caller observe threshold changes
+// too. No keyvals are copied and no Valuers are evaluated for suppressed
+// events.
 type Levels struct {
-	ctx      *log.Context
-	levelKey string
+	ctx       *log.Context
+	levelKey  string
+	threshold *Threshold
 
 	// We have a choice between storing level values in string fields or
 	// making a separate context for each level. When using string fields the
@@ -34,8 +42,9 @@ type Levels struct {
 // New creates a new leveled logger, wrapping the passed logger.
 func New(logger log.Logger, options ...Option) Levels {
 	l := Levels{
-		ctx:      log.NewContext(logger),
-		levelKey: "level",
+		ctx:       log.NewContext(logger),
+		levelKey:  "level",
+		threshold: &Threshold{},
 
 		debugValue: "debug",
 		infoValue:  "info",
@@ -54,6 +63,7 @@ func (l Levels) With(keyvals ...interface{}) Levels {
 	return Levels{
 		ctx:        l.ctx.With(keyvals...),
 		levelKey:   l.levelKey,
+		threshold:  l.threshold,
 		debugValue: l.debugValue,
 		infoValue:  l.infoValue,
 		warnValue:  l.warnValue,
@@ -64,27 +74,61 @@ func (l Levels) With(keyvals ...interface{}) Levels {
 
 // Debug returns a debug level logger.
 func (l Levels) Debug() log.Logger {
-	return l.ctx.WithPrefix(l.levelKey, l.debugValue)
+	return l.leveled(DebugLevel, l.debugValue)
 }
 
 // Info returns an info level logger.
 func (l Levels) Info() log.Logger {
-	return l.ctx.WithPrefix(l.levelKey, l.infoValue)
+	return l.leveled(InfoLevel, l.infoValue)
 }
 
 // Warn returns a warning level logger.
 func (l Levels) Warn() log.Logger {
-	return l.ctx.WithPrefix(l.levelKey, l.warnValue)
+	return l.leveled(WarnLevel, l.warnValue)
 }
 
 // Error returns an error level logger.
 func (l Levels) Error() log.Logger {
-	return l.ctx.WithPrefix(l.levelKey, l.errorValue)
+	return l.leveled(ErrorLevel, l.errorValue)
 }
 
 // Crit returns a critical level logger.
 func (l Levels) Crit() log.Logger {
-	return l.ctx.WithPrefix(l.levelKey, l.critValue)
+	return l.leveled(CritLevel, l.critValue)
+}
+
+// Threshold returns the minimum level shared by the receiver and all leveled
+// loggers derived from it. It may be used to change the minimum at runtime.
+func (l Levels) Threshold() *Threshold {
+	return l.threshold
+}
+
+func (l Levels) leveled(level Level, value string) log.Logger {
+	return leveledLogger{
+		ctx:       l.ctx,
+		levelKey:  l.levelKey,
+		value:     value,
+		level:     level,
+		threshold: l.threshold,
+	}
+}
+
+// leveledLogger logs at a single level. It reads the threshold on each call to
+// Log, and only then prefixes the level field, so that suppressed events cost
+// no more than the threshold check.
+type leveledLogger struct {
+	ctx       *log.Context
+	levelKey  string
+	value     string
+	level     Level
+	threshold *Threshold
+}
+
+func (l leveledLogger) Log(keyvals ...interface{}) error {
+	if !l.threshold.Allows(l.level) {
+		return nil
+	}
+	return l.ctx.WithPrefix(l.levelKey, l.value).Log(keyvals...)
 }
 
 // Option sets a parameter for leveled loggers.
@@ -96,6 +140,18 @@ func Key(key string) Option {
 	return func(l *Levels) { l.levelKey = key }
 }
 
+// MinLevel sets the minimum level that will be emitted. Events at less severe
+// levels are discarded. By default, all levels are emitted.
+func MinLevel(min Level) Option {
+	return func(l *Levels) { l.threshold = NewThreshold(min) }
+}
+
+// SharedThreshold sets the Threshold used to filter events. It allows a single
+// Threshold to control several independently constructed leveled loggers.
+func SharedThreshold(t *Threshold) Option {
+	return func(l *Levels) { l.threshold = t }
+}
+
 // DebugValue sets the value for the field used to indicate the debug log
 // level. By default, the value is "debug".
 func DebugValue(value string) Option {
@@ -54,6 +54,103 @@ func TestModifiedLevels(t *testing.T) {
 	}
 }
 
+func TestMinLevel(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := levels.New(log.NewLogfmtLogger(&buf), levels.MinLevel(levels.WarnLevel))
+
+	logger.Debug().Log("msg", "suppressed")
+	logger.Info().Log("msg", "suppressed")
+	if want, have := "", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+
+	logger.Warn().Log("msg", "kept")
+	if want, have := "level=warn msg=kept\n", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+}
+
+func TestThresholdChange(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := levels.New(log.NewLogfmtLogger(&buf), levels.MinLevel(levels.InfoLevel))
+	child := logger.With("component", "child")
+
+	child.Debug().Log("msg", "suppressed")
+	if want, have := "", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+
+	// The threshold is shared with loggers derived via With.
+	logger.Threshold().SetLevel(levels.DebugLevel)
+	child.Debug().Log("msg", "kept")
+	if want, have := "level=debug component=child msg=kept\n", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+}
+
+func TestRetainedLoggerThresholdChange(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := levels.New(log.NewLogfmtLogger(&buf), levels.MinLevel(levels.InfoLevel))
+	debug := logger.Debug()
+
+	debug.Log("msg", "suppressed")
+	if want, have := "", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+
+	// Loggers kept by the caller read the threshold on each Log.
+	logger.Threshold().SetLevel(levels.DebugLevel)
+	debug.Log("msg", "kept")
+	if want, have := "level=debug msg=kept\n", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+
+	buf.Reset()
+	logger.Threshold().SetLevel(levels.WarnLevel)
+	debug.Log("msg", "suppressed")
+	if want, have := "", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+}
+
+func TestSharedThreshold(t *testing.T) {
+	var (
+		buf       = bytes.Buffer{}
+		threshold = levels.NewThreshold(levels.ErrorLevel)
+		a         = levels.New(log.NewLogfmtLogger(&buf), levels.SharedThreshold(threshold))
+		b         = levels.New(log.NewLogfmtLogger(&buf), levels.SharedThreshold(threshold))
+	)
+
+	a.Warn().Log("msg", "suppressed")
+	b.Warn().Log("msg", "suppressed")
+	if want, have := "", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+
+	threshold.SetLevel(levels.WarnLevel)
+	a.Warn().Log("msg", "a")
+	b.Warn().Log("msg", "b")
+	if want, have := "level=warn msg=a\nlevel=warn msg=b\n", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+}
+
+func TestSuppressedValuerNotEvaluated(t *testing.T) {
+	var evaluated int
+	valuer := log.Valuer(func() interface{} { evaluated++; return evaluated })
+	logger := levels.New(log.NewNopLogger(), levels.MinLevel(levels.InfoLevel)).With("n", valuer)
+
+	logger.Debug().Log("msg", "suppressed")
+	if want, have := 0, evaluated; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	logger.Info().Log("msg", "kept")
+	if want, have := 1, evaluated; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
 func ExampleLevels() {
 	logger := levels.New(log.NewLogfmtLogger(os.Stdout))
 	logger.Debug().Log("msg", "hello")
@@ -63,3 +160,16 @@ func ExampleLevels() {
 	// level=debug msg=hello
 	// level=warn context=foo err=error
 }
+
+func ExampleMinLevel() {
+	logger := levels.New(log.NewLogfmtLogger(os.Stdout), levels.MinLevel(levels.InfoLevel))
+	logger.Debug().Log("msg", "hello")
+	logger.Info().Log("msg", "world")
+
+	logger.Threshold().SetLevel(levels.DebugLevel)
+	logger.Debug().Log("msg", "hello again")
+
+	// Output:
+	// level=info msg=world
+	// level=debug msg="hello again"
+}
@@ -0,0 +1,81 @@
+package levels
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the severity of a log event. Levels are ordered from least to most
+// severe, so a Threshold allows every level greater than or equal to its own.
+type Level int32
+
+// The levels supported by the Levels type, in order of increasing severity.
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	CritLevel
+)
+
+// String returns the canonical name of the level. It is independent of the
+// values configured via DebugValue, InfoValue, and so on.
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case CritLevel:
+		return "crit"
+	default:
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+}
+
+// ParseLevel returns the Level with the given canonical name, as returned by
+// Level.String. Matching is case-insensitive.
+func ParseLevel(s string) (Level, error) {
+	for l := DebugLevel; l <= CritLevel; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown level %q", s)
+}
+
+// Threshold holds the minimum level a leveled logger will emit. It may be
+// changed at any time, and is safe for concurrent use by multiple goroutines.
+// The zero value allows all levels.
+//
+// A single Threshold is shared by a Levels value and all of the leveled
+// loggers derived from it via With, so changing it affects all of them.
+type Threshold struct {
+	level int32
+}
+
+// NewThreshold returns a Threshold allowing min and all more severe levels.
+func NewThreshold(min Level) *Threshold {
+	return &Threshold{level: int32(min)}
+}
+
+// Level returns the current minimum level.
+func (t *Threshold) Level() Level {
+	return Level(atomic.LoadInt32(&t.level))
+}
+
+// SetLevel changes the minimum level. SetLevel may be called concurrently
+// with calls to Log from other goroutines.
+func (t *Threshold) SetLevel(min Level) {
+	atomic.StoreInt32(&t.level, int32(min))
+}
+
+// Allows returns true if events at level l should be emitted.
+func (t *Threshold) Allows(l Level) bool {
+	return l >= t.Level()
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// EndpointFieldKeys are the keys of the fields added by the middleware
+// returned by EndpointInstrumenting. Backends that require field keys to be
+// declared in advance, like Prometheus, should be constructed with them.
+var EndpointFieldKeys = []string{"method", "success", "error"}
+
+// EndpointInstrumenting returns an endpoint middleware that counts every
+// invocation of the endpoint, and records its duration. Both metrics are
+// observed with three fields: "method", which is the passed method name;
+// "success", which is "true" if the endpoint returned no error, and "false"
+// otherwise; and "error", the class of the returned error, as determined by
+// the error classifier.
+//
+// Any backend may be used, including those constructed via package
+// metrics/provider; wrap a Histogram with NewTimeHistogram to measure
+// durations. Backends that ignore fields aggregate all invocations.
+func EndpointInstrumenting(method string, requests Counter, duration TimeHistogram, options ...EndpointOption) endpoint.Middleware {
+	e := endpointInstrumenting{
+		classify: DefaultErrorClass,
+	}
+	for _, option := range options {
+		option(&e)
+	}
+	methodField := Field{Key: "method", Value: method}
+	requests = requests.With(methodField)
+	duration = duration.With(methodField)
+
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func(begin time.Time) {
+				var (
+					successField = Field{Key: "success", Value: fmt.Sprint(err == nil)}
+					errorField   = Field{Key: "error", Value: e.classify(err)}
+				)
+				requests.With(successField).With(errorField).Add(1)
+				duration.With(successField).With(errorField).Observe(time.Since(begin))
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
+
+type endpointInstrumenting struct {
+	classify func(error) string
+}
+
+// EndpointOption sets an optional parameter for EndpointInstrumenting.
+type EndpointOption func(*endpointInstrumenting)
+
+// EndpointErrorClassifier sets the function used to derive the value of the "error"
+// field from the error returned by the endpoint. It's passed nil for
+// successful invocations. Classes become field values, so there should be few
+// of them; never return the error message itself. The default is
+// DefaultErrorClass.
+func EndpointErrorClassifier(classify func(error) string) EndpointOption {
+	return func(e *endpointInstrumenting) { e.classify = classify }
+}
+
+// DefaultErrorClass returns "none" for a nil error, "timeout" or "canceled"
+// for the errors of a context that's done, and "error" otherwise.
+func DefaultErrorClass(err error) string {
+	switch err {
+	case nil:
+		return "none"
+	case context.DeadlineExceeded:
+		return "timeout"
+	case context.Canceled:
+		return "canceled"
+	default:
+		return "error"
+	}
+}
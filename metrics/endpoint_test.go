@@ -0,0 +1,104 @@
+package metrics_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+func TestEndpointInstrumenting(t *testing.T) {
+	var (
+		requests = &fieldCounter{recorder: &recorder{}}
+		duration = &fieldHistogram{recorder: &recorder{}}
+		mw       = metrics.EndpointInstrumenting("Sum", requests, duration)
+	)
+
+	for _, err := range []error{nil, errors.New("boom"), context.DeadlineExceeded} {
+		e := mw(func(context.Context, interface{}) (interface{}, error) { return struct{}{}, err })
+		e(context.Background(), struct{}{})
+	}
+
+	want := []string{
+		"method=Sum success=true error=none",
+		"method=Sum success=false error=error",
+		"method=Sum success=false error=timeout",
+	}
+	for _, r := range []*recorder{requests.recorder, duration.recorder} {
+		have := r.observations()
+		if len(want) != len(have) {
+			t.Fatalf("want %d observations, have %d", len(want), len(have))
+		}
+		for i := range want {
+			if want[i] != have[i] {
+				t.Errorf("observation %d: want %q, have %q", i, want[i], have[i])
+			}
+		}
+	}
+}
+
+func TestEndpointErrorClassifier(t *testing.T) {
+	requests := &fieldCounter{recorder: &recorder{}}
+	mw := metrics.EndpointInstrumenting("Sum", requests, &fieldHistogram{recorder: &recorder{}}, metrics.EndpointErrorClassifier(func(err error) string {
+		if err != nil {
+			return "custom"
+		}
+		return "ok"
+	}))
+	e := mw(func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("boom") })
+	e(context.Background(), struct{}{})
+
+	if want, have := "method=Sum success=false error=custom", requests.observations()[0]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+// recorder records the fields of each observation, in order.
+type recorder struct {
+	mtx sync.Mutex
+	obs []string
+}
+
+func (r *recorder) observe(fields string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.obs = append(r.obs, fields)
+}
+
+func (r *recorder) observations() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]string{}, r.obs...)
+}
+
+func join(fields string, f metrics.Field) string {
+	if fields != "" {
+		fields += " "
+	}
+	return fields + f.Key + "=" + f.Value
+}
+
+type fieldCounter struct {
+	*recorder
+	fields string
+}
+
+func (c *fieldCounter) Name() string { return "requests" }
+func (c *fieldCounter) With(f metrics.Field) metrics.Counter {
+	return &fieldCounter{c.recorder, join(c.fields, f)}
+}
+func (c *fieldCounter) Add(uint64) { c.observe(c.fields) }
+
+type fieldHistogram struct {
+	*recorder
+	fields string
+}
+
+func (h *fieldHistogram) With(f metrics.Field) metrics.TimeHistogram {
+	return &fieldHistogram{h.recorder, join(h.fields, f)}
+}
+func (h *fieldHistogram) Observe(time.Duration) { h.observe(h.fields) }
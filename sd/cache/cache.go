@@ -1,13 +1,17 @@
 package cache
 
 import (
+	"fmt"
 	"io"
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/go-kit/kit/sd"
 )
 
@@ -15,12 +19,52 @@ import (
 // system via a subscriber, and makes them available to consumers. Cache is
 // meant to be embedded inside of a concrete subscriber, and can serve Service
 // invocations directly.
+//
+// Subscribers report failures to reach their service discovery system via
+// SetError. While it fails, the cache keeps serving the last known-good set of
+// endpoints for a while, see MaxStaleness. Afterwards, or if there are no
+// known-good endpoints to serve, Endpoints and Instances return an Error, so
+// that consumers can tell a broken service discovery system from a service
+// without instances.
 type Cache struct {
-	mtx     sync.RWMutex
-	factory sd.Factory
-	cache   map[string]endpointCloser
-	slice   atomic.Value // []endpoint.Endpoint
-	logger  log.Logger
+	mtx      sync.RWMutex
+	factory  sd.Factory
+	cache    map[string]endpointCloser
+	slice    atomic.Value // []endpoint.Endpoint
+	byName   atomic.Value // map[string]endpoint.Endpoint
+	meta     atomic.Value // map[string]sd.Instance
+	status   atomic.Value // status
+	logger   log.Logger
+	maxStale time.Duration // negative means unbounded
+	now      func() time.Time
+	names    []string // of the current endpoints, sorted
+	notified map[chan<- sd.Event]*notification
+
+	instances       metrics.Gauge
+	updates         metrics.Counter
+	factoryErrors   metrics.Counter
+	discoveryErrors metrics.Counter
+}
+
+type status struct {
+	err          error     // of the most recent refresh; nil if it succeeded
+	lastUpdate   time.Time // of the most recent successful refresh
+	failingSince time.Time // of the first failed refresh since then
+}
+
+// Error is returned by a Cache whose service discovery system is failing,
+// once it no longer serves the last known-good endpoints.
+type Error struct {
+	Err        error     // the most recent error reported via SetError
+	LastUpdate time.Time // of the last successful update; zero if none
+}
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	if e.LastUpdate.IsZero() {
+		return fmt.Sprintf("service discovery failed: %v (never updated)", e.Err)
+	}
+	return fmt.Sprintf("service discovery failed: %v (last updated %s)", e.Err, e.LastUpdate.Format(time.RFC3339))
 }
 
 type endpointCloser struct {
@@ -28,13 +72,56 @@ type endpointCloser struct {
 	io.Closer
 }
 
+// Option sets an optional parameter for caches.
+type Option func(*Cache)
+
+// MaxStaleness sets how long the cache keeps serving the last known-good
+// endpoints after its service discovery system starts failing. Zero means
+// errors are returned right away. By default, the last known-good endpoints
+// are served for as long as the failure lasts.
+func MaxStaleness(d time.Duration) Option {
+	return func(c *Cache) { c.maxStale = d }
+}
+
+// Instrumenting makes the cache report the number of endpoints it serves, and
+// count updates, instances for which the factory failed to make an endpoint,
+// and errors reported via SetError. A failing factory otherwise only shows up
+// in the logs. Subscribers built on the cache take this option like any
+// other; use Field to tell the metrics of several caches apart, e.g.
+//
+//	field := metrics.Field{Key: "service", Value: "search"}
+//	cache.Instrumenting(instances.With(field), updates.With(field), factoryErrors.With(field), discoveryErrors.With(field))
+func Instrumenting(instances metrics.Gauge, updates, factoryErrors, discoveryErrors metrics.Counter) Option {
+	return func(c *Cache) {
+		c.instances = instances
+		c.updates = updates
+		c.factoryErrors = factoryErrors
+		c.discoveryErrors = discoveryErrors
+	}
+}
+
 // New returns a new, empty endpoint cache.
-func New(factory sd.Factory, logger log.Logger) *Cache {
-	return &Cache{
-		factory: factory,
-		cache:   map[string]endpointCloser{},
-		logger:  logger,
+func New(factory sd.Factory, logger log.Logger, options ...Option) *Cache {
+	c := &Cache{
+		factory:         factory,
+		cache:           map[string]endpointCloser{},
+		logger:          logger,
+		maxStale:        -1,
+		now:             time.Now,
+		notified:        map[chan<- sd.Event]*notification{},
+		instances:       discard.NewGauge(""),
+		updates:         discard.NewCounter(""),
+		factoryErrors:   discard.NewCounter(""),
+		discoveryErrors: discard.NewCounter(""),
 	}
+	for _, option := range options {
+		option(c)
+	}
+	c.slice.Store([]endpoint.Endpoint{})
+	c.byName.Store(map[string]endpoint.Endpoint{})
+	c.meta.Store(map[string]sd.Instance{})
+	c.status.Store(status{})
+	return c
 }
 
 // Update should be invoked by clients with a complete set of current instance
@@ -42,9 +129,30 @@ func New(factory sd.Factory, logger log.Logger) *Cache {
 // the factory, closes old endpoints when they disappear, and persists existing
 // endpoints if they survive through an update.
 func (c *Cache) Update(instances []string) {
+	described := make([]sd.Instance, len(instances))
+	for i, instance := range instances {
+		described[i] = sd.Instance{Addr: instance}
+	}
+	c.UpdateInstances(described)
+}
+
+// UpdateInstances is like Update, but takes instances along with their
+// metadata. Endpoints are keyed by address only, so an instance whose
+// metadata changes keeps its endpoint.
+func (c *Cache) UpdateInstances(described []sd.Instance) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
 
+	// Index the metadata; the last of any duplicate addresses wins.
+	meta := make(map[string]sd.Instance, len(described))
+	instances := make([]string, 0, len(described))
+	for _, d := range described {
+		if _, ok := meta[d.Addr]; !ok {
+			instances = append(instances, d.Addr)
+		}
+		meta[d.Addr] = d
+	}
+
 	// Deterministic order (for later).
 	sort.Strings(instances)
 
@@ -62,6 +170,7 @@ func (c *Cache) Update(instances []string) {
 		service, closer, err := c.factory(instance)
 		if err != nil {
 			c.logger.Log("instance", instance, "err", err)
+			c.factoryErrors.Add(1)
 			continue
 		}
 		cache[instance] = endpointCloser{service, closer}
@@ -74,23 +183,100 @@ func (c *Cache) Update(instances []string) {
 		}
 	}
 
-	// Populate the slice of endpoints.
+	// Populate the slice and map of endpoints.
 	slice := make([]endpoint.Endpoint, 0, len(cache))
+	byName := make(map[string]endpoint.Endpoint, len(cache))
+	names := make([]string, 0, len(cache))
 	for _, instance := range instances {
 		// A bad factory may mean an instance is not present.
 		if _, ok := cache[instance]; !ok {
 			continue
 		}
 		slice = append(slice, cache[instance].Endpoint)
+		byName[instance] = cache[instance].Endpoint
+		names = append(names, instance)
+	}
+	for instance := range meta {
+		if _, ok := cache[instance]; !ok {
+			delete(meta, instance)
+		}
 	}
 
 	// Swap and trigger GC for old copies.
 	c.slice.Store(slice)
+	c.byName.Store(byName)
+	c.meta.Store(meta)
+	c.status.Store(status{lastUpdate: c.now()})
 	c.cache = cache
+	c.names = names
+	c.updates.Add(1)
+	c.instances.Set(float64(len(slice)))
+	c.notify(nil)
+}
+
+// SetError should be invoked by clients whenever they fail to get the current
+// set of instances from their service discovery system. The error is cleared
+// by the next Update.
+func (c *Cache) SetError(err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	st := c.status.Load().(status)
+	if st.err == nil {
+		st.failingSince = c.now()
+	}
+	st.err = err
+	c.status.Store(st)
+	c.discoveryErrors.Add(1)
+	c.notify(err)
+}
+
+// check returns an Error if the service discovery system is failing, and the
+// n known-good endpoints shouldn't be served anymore.
+func (c *Cache) check(n int) error {
+	st := c.status.Load().(status)
+	if st.err == nil {
+		return nil
+	}
+	if n > 0 && (c.maxStale < 0 || c.now().Sub(st.failingSince) < c.maxStale) {
+		return nil
+	}
+	return Error{Err: st.err, LastUpdate: st.lastUpdate}
 }
 
 // Endpoints yields the current set of (presumably identical) endpoints, ordered
-// lexicographically by the corresponding instance string.
-func (c *Cache) Endpoints() []endpoint.Endpoint {
-	return c.slice.Load().([]endpoint.Endpoint)
+// lexicographically by the corresponding instance string. It returns an Error
+// if the service discovery system is failing, and the last known-good
+// endpoints are empty or too stale to serve.
+func (c *Cache) Endpoints() ([]endpoint.Endpoint, error) {
+	endpoints := c.slice.Load().([]endpoint.Endpoint)
+	if err := c.check(len(endpoints)); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// Instances yields the current set of endpoints, keyed by the corresponding
+// instance string. The returned map must not be modified; a new map is
+// produced by every Update. Errors are returned like by Endpoints.
+func (c *Cache) Instances() (map[string]endpoint.Endpoint, error) {
+	instances := c.byName.Load().(map[string]endpoint.Endpoint)
+	if err := c.check(len(instances)); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// Status reports the state of the service discovery system, as told by the
+// most recent calls to Update and SetError.
+func (c *Cache) Status() sd.Status {
+	st := c.status.Load().(status)
+	return sd.Status{LastUpdate: st.lastUpdate, Err: st.err}
+}
+
+// Metadata yields the current set of instances with their metadata, keyed by
+// instance string. Instances given to Update rather than UpdateInstances have
+// no metadata beyond their address. The returned map must not be modified.
+func (c *Cache) Metadata() map[string]sd.Instance {
+	return c.meta.Load().(map[string]sd.Instance)
 }
@@ -8,6 +8,8 @@ import (
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/sd"
 )
 
 func TestCache(t *testing.T) {
@@ -29,9 +31,12 @@ func TestCache(t *testing.T) {
 	case <-time.After(time.Millisecond):
 		t.Logf("no closures yet, good")
 	}
-	if want, have := 2, len(cache.Endpoints()); want != have {
+	if want, have := 2, len(endpoints(t, cache)); want != have {
 		t.Errorf("want %d, have %d", want, have)
 	}
+	if instances, _ := cache.Instances(); instances["b"] == nil {
+		t.Errorf("instance b missing")
+	}
 
 	// Duplicate, should be no-op
 	cache.Update([]string{"a", "b"})
@@ -43,7 +48,7 @@ func TestCache(t *testing.T) {
 	case <-time.After(time.Millisecond):
 		t.Logf("no closures yet, good")
 	}
-	if want, have := 2, len(cache.Endpoints()); want != have {
+	if want, have := 2, len(endpoints(t, cache)); want != have {
 		t.Errorf("want %d, have %d", want, have)
 	}
 
@@ -57,7 +62,7 @@ func TestCache(t *testing.T) {
 	case <-time.After(time.Second):
 		t.Errorf("didn't close the deleted instance in time")
 	}
-	if want, have := 1, len(cache.Endpoints()); want != have {
+	if want, have := 1, len(endpoints(t, cache)); want != have {
 		t.Errorf("want %d, have %d", want, have)
 	}
 
@@ -70,7 +75,7 @@ func TestCache(t *testing.T) {
 	case <-time.After(time.Second):
 		t.Errorf("didn't close the deleted instance in time")
 	}
-	if want, have := 0, len(cache.Endpoints()); want != have {
+	if want, have := 0, len(endpoints(t, cache)); want != have {
 		t.Errorf("want %d, have %d", want, have)
 	}
 }
@@ -81,11 +86,189 @@ func TestBadFactory(t *testing.T) {
 	}, log.NewNopLogger())
 
 	cache.Update([]string{"foo:1234", "bar:5678"})
-	if want, have := 0, len(cache.Endpoints()); want != have {
+	if want, have := 0, len(endpoints(t, cache)); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestCacheMetadata(t *testing.T) {
+	var (
+		created int
+		f       = func(string) (endpoint.Endpoint, io.Closer, error) { created++; return endpoint.Nop, nil, nil }
+		cache   = New(f, log.NewNopLogger())
+	)
+
+	cache.UpdateInstances([]sd.Instance{
+		{Addr: "a", Weight: 2, Zone: "us-east-1a"},
+		{Addr: "b", Tags: []string{"canary"}},
+	})
+	if want, have := 2, len(endpoints(t, cache)); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := 2, cache.Metadata()["a"].Weight; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := true, cache.Metadata()["b"].HasTag("canary"); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Changing metadata keeps the endpoint.
+	cache.UpdateInstances([]sd.Instance{
+		{Addr: "a", Weight: 5, Zone: "us-east-1a"},
+		{Addr: "b"},
+	})
+	if want, have := 2, created; want != have {
+		t.Errorf("want %d endpoints created, have %d", want, have)
+	}
+	if want, have := 5, cache.Metadata()["a"].Weight; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := false, cache.Metadata()["b"].HasTag("canary"); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Plain updates carry addresses only.
+	cache.Update([]string{"a"})
+	if want, have := (sd.Instance{Addr: "a"}), cache.Metadata()["a"]; want.Addr != have.Addr || want.Weight != have.Weight || want.Zone != have.Zone {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	if want, have := 1, len(cache.Metadata()); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestCacheError(t *testing.T) {
+	var (
+		now   = time.Unix(1000, 0)
+		f     = func(string) (endpoint.Endpoint, io.Closer, error) { return endpoint.Nop, nil, nil }
+		cache = New(f, log.NewNopLogger(), MaxStaleness(time.Minute))
+		fail  = errors.New("discovery is down")
+	)
+	cache.now = func() time.Time { return now }
+
+	// Nothing known-good to serve.
+	cache.SetError(fail)
+	_, err := cache.Endpoints()
+	if want, have := (Error{Err: fail}), err; want != have {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	cache.Update([]string{"a", "b"})
+	if want, have := 2, len(endpoints(t, cache)); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	lastUpdate := now
+
+	// Stale, but still served.
+	now = now.Add(time.Hour)
+	cache.SetError(fail)
+	now = now.Add(59 * time.Second)
+	cache.SetError(fail) // doesn't extend the staleness period
+	if want, have := 2, len(endpoints(t, cache)); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if _, err := cache.Instances(); err != nil {
+		t.Error(err)
+	}
+	if want, have := (sd.Status{LastUpdate: lastUpdate, Err: fail}), cache.Status(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Too stale.
+	now = now.Add(time.Second)
+	_, err = cache.Endpoints()
+	if want, have := (Error{Err: fail, LastUpdate: lastUpdate}), err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if _, err := cache.Instances(); err == nil {
+		t.Error("want error, have none")
+	}
+
+	// Recovered.
+	cache.Update([]string{"a"})
+	if want, have := 1, len(endpoints(t, cache)); want != have {
 		t.Errorf("want %d, have %d", want, have)
 	}
 }
 
+func TestCacheStaleForever(t *testing.T) {
+	var (
+		f     = func(string) (endpoint.Endpoint, io.Closer, error) { return endpoint.Nop, nil, nil }
+		cache = New(f, log.NewNopLogger())
+	)
+
+	cache.Update([]string{"a"})
+	cache.SetError(errors.New("discovery is down"))
+	cache.now = func() time.Time { return time.Now().Add(24 * time.Hour) }
+	if want, have := 1, len(endpoints(t, cache)); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	// A service without instances isn't an error.
+	cache.Update([]string{})
+	if want, have := 0, len(endpoints(t, cache)); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func endpoints(t *testing.T, c *Cache) []endpoint.Endpoint {
+	endpoints, err := c.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return endpoints
+}
+
 type closer chan struct{}
 
 func (c closer) Close() error { close(c); return nil }
+
+func TestCacheInstrumenting(t *testing.T) {
+	var (
+		instances       = &gauge{}
+		updates         = &counter{}
+		factoryErrors   = &counter{}
+		discoveryErrors = &counter{}
+		f               = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			if instance == "bad" {
+				return nil, nil, errors.New("bad instance")
+			}
+			return endpoint.Nop, nil, nil
+		}
+		cache = New(f, log.NewNopLogger(), Instrumenting(instances, updates, factoryErrors, discoveryErrors))
+	)
+
+	cache.Update([]string{"a", "b", "bad"})
+	cache.Update([]string{"a", "bad"})
+	cache.SetError(errors.New("discovery is down"))
+
+	if want, have := 1.0, instances.Get(); want != have {
+		t.Errorf("instances: want %v, have %v", want, have)
+	}
+	for name, c := range map[string]struct {
+		want uint64
+		have *counter
+	}{
+		"updates":          {2, updates},
+		"factory errors":   {2, factoryErrors},
+		"discovery errors": {1, discoveryErrors},
+	} {
+		if c.want != c.have.value {
+			t.Errorf("%s: want %d, have %d", name, c.want, c.have.value)
+		}
+	}
+}
+
+type counter struct{ value uint64 }
+
+func (c *counter) Name() string                       { return "counter" }
+func (c *counter) With(metrics.Field) metrics.Counter { return c }
+func (c *counter) Add(delta uint64)                   { c.value += delta }
+
+type gauge struct{ value float64 }
+
+func (g *gauge) Name() string                     { return "gauge" }
+func (g *gauge) With(metrics.Field) metrics.Gauge { return g }
+func (g *gauge) Set(value float64)                { g.value = value }
+func (g *gauge) Add(delta float64)                { g.value += delta }
+func (g *gauge) Get() float64                     { return g.value }
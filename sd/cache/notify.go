@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/go-kit/kit/sd"
+)
+
+// Notify implements the NotifyingSubscriber interface. Events are sent for
+// every Update that changes the instances, and every SetError.
+func (c *Cache) Notify(ch chan<- sd.Event) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.notified[ch]; ok {
+		return
+	}
+	n := &notification{
+		ch:      ch,
+		pending: make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	n.push(notificationState{instances: c.names, err: c.status.Load().(status).err}, true)
+	c.notified[ch] = n
+	go n.loop()
+}
+
+// StopNotify implements the NotifyingSubscriber interface. No events are sent
+// to the channel once it returns.
+func (c *Cache) StopNotify(ch chan<- sd.Event) {
+	c.mtx.Lock()
+	n, ok := c.notified[ch]
+	delete(c.notified, ch)
+	c.mtx.Unlock()
+	if ok {
+		n.stop()
+	}
+}
+
+// notify pushes the current state to every notified channel. It must be
+// called with the mutex held.
+func (c *Cache) notify(err error) {
+	for _, n := range c.notified {
+		n.push(notificationState{instances: c.names, err: err}, err != nil)
+	}
+}
+
+type notificationState struct {
+	instances []string // sorted
+	err       error
+}
+
+// notification relays the state of a cache to a single channel. The latest
+// state is kept until the consumer is ready to receive it, so that a slow
+// consumer doesn't hold up the cache.
+type notification struct {
+	ch      chan<- sd.Event
+	mtx     sync.Mutex
+	latest  notificationState
+	force   bool          // send the latest state even if it's unchanged
+	pending chan struct{} // signals a new latest state
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func (n *notification) push(s notificationState, force bool) {
+	n.mtx.Lock()
+	n.latest = s
+	n.force = n.force || force
+	n.mtx.Unlock()
+	select {
+	case n.pending <- struct{}{}:
+	default: // already pending
+	}
+}
+
+func (n *notification) loop() {
+	defer close(n.done)
+	var (
+		last    []string // as of the previous event sent
+		lastErr error
+	)
+	for {
+		select {
+		case <-n.pending:
+		case <-n.quit:
+			return
+		}
+
+		n.mtx.Lock()
+		s, force := n.latest, n.force
+		n.force = false
+		n.mtx.Unlock()
+
+		added, removed := diff(last, s.instances)
+		if !force && s.err == nil && lastErr == nil && len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		e := sd.Event{
+			Instances: s.instances,
+			Added:     added,
+			Removed:   removed,
+			Err:       s.err,
+		}
+		select {
+		case n.ch <- e:
+			last, lastErr = s.instances, s.err
+		case <-n.quit:
+			return
+		}
+	}
+}
+
+func (n *notification) stop() {
+	close(n.quit)
+	<-n.done
+}
+
+// diff returns the strings in b but not a, and in a but not b. Both must be
+// sorted.
+func diff(a, b []string) (added, removed []string) {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			removed = append(removed, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			added = append(added, b[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return added, removed
+}
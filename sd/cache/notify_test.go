@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestNotify(t *testing.T) {
+	var (
+		f     = func(string) (endpoint.Endpoint, io.Closer, error) { return endpoint.Nop, nil, nil }
+		cache = New(f, log.NewNopLogger())
+		ch    = make(chan sd.Event)
+		fail  = errors.New("discovery is down")
+	)
+	cache.Update([]string{"a", "b"})
+
+	cache.Notify(ch)
+	defer cache.StopNotify(ch)
+	assertEvent(t, ch, sd.Event{Instances: []string{"a", "b"}, Added: []string{"a", "b"}})
+
+	cache.Update([]string{"b", "c"})
+	assertEvent(t, ch, sd.Event{Instances: []string{"b", "c"}, Added: []string{"c"}, Removed: []string{"a"}})
+
+	cache.SetError(fail)
+	assertEvent(t, ch, sd.Event{Instances: []string{"b", "c"}, Err: fail})
+
+	// Recovering is an event, even without changes to the instances.
+	cache.Update([]string{"b", "c"})
+	assertEvent(t, ch, sd.Event{Instances: []string{"b", "c"}})
+
+	// Updates that change nothing aren't.
+	cache.Update([]string{"c", "b"})
+	assertNoEvent(t, ch)
+}
+
+func TestNotifyCoalesces(t *testing.T) {
+	var (
+		f     = func(string) (endpoint.Endpoint, io.Closer, error) { return endpoint.Nop, nil, nil }
+		cache = New(f, log.NewNopLogger())
+		ch    = make(chan sd.Event)
+	)
+
+	cache.Notify(ch)
+	defer cache.StopNotify(ch)
+
+	// The consumer isn't receiving, yet updates don't block.
+	cache.Update([]string{"a"})
+	cache.Update([]string{"a", "b"})
+	cache.Update([]string{"b", "c"})
+
+	// The first event may describe the instances at the time of Notify, or
+	// any later ones, but the last one is the latest state.
+	var e sd.Event
+	for e = range receive(ch, 100*time.Millisecond) {
+	}
+	if want, have := "[b c]", fmt.Sprint(e.Instances); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestStopNotify(t *testing.T) {
+	var (
+		f     = func(string) (endpoint.Endpoint, io.Closer, error) { return endpoint.Nop, nil, nil }
+		cache = New(f, log.NewNopLogger())
+		ch    = make(chan sd.Event)
+	)
+
+	// Stopping doesn't wait for the consumer to receive pending events.
+	cache.Notify(ch)
+	cache.Update([]string{"a"})
+	cache.StopNotify(ch)
+
+	cache.Update([]string{"a", "b"})
+	assertNoEvent(t, ch)
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		a, b           []string
+		added, removed string
+	}{
+		{nil, nil, "[]", "[]"},
+		{nil, []string{"a", "b"}, "[a b]", "[]"},
+		{[]string{"a", "b"}, nil, "[]", "[a b]"},
+		{[]string{"a", "c", "e"}, []string{"b", "c", "d"}, "[b d]", "[a e]"},
+	} {
+		added, removed := diff(tc.a, tc.b)
+		if want, have := tc.added, fmt.Sprint(added); want != have {
+			t.Errorf("%v → %v: added: want %s, have %s", tc.a, tc.b, want, have)
+		}
+		if want, have := tc.removed, fmt.Sprint(removed); want != have {
+			t.Errorf("%v → %v: removed: want %s, have %s", tc.a, tc.b, want, have)
+		}
+	}
+}
+
+func assertEvent(t *testing.T, ch <-chan sd.Event, want sd.Event) {
+	select {
+	case have := <-ch:
+		if fmt.Sprint(want) != fmt.Sprint(have) {
+			t.Fatalf("want %v, have %v", want, have)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("want %v, have no event", want)
+	}
+}
+
+func assertNoEvent(t *testing.T, ch <-chan sd.Event) {
+	select {
+	case have := <-ch:
+		t.Fatalf("want no event, have %v", have)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// receive relays events until none arrived for the timeout.
+func receive(ch <-chan sd.Event, timeout time.Duration) <-chan sd.Event {
+	out := make(chan sd.Event)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case e := <-ch:
+				out <- e
+			case <-time.After(timeout):
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,173 @@
+package sd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd/internal/identity"
+)
+
+// Union yields the endpoints of several subscribers combined, e.g. those from
+// a service discovery system along with a FixedInstanceSubscriber of
+// well-known instances. Subscribers that implement InstanceSubscriber are
+// deduplicated by instance string, the first subscriber to yield an instance
+// winning. Endpoints of other subscribers, like a FixedSubscriber, can't be
+// told apart, and are all included.
+//
+// A subscriber that fails is left out. Union only fails if all of them do, in
+// which case it returns the error of the last one.
+type Union struct {
+	subscribers []Subscriber
+
+	mtx       sync.Mutex
+	results   []interface{} // of the subscribers, the endpoints were built from
+	endpoints []endpoint.Endpoint
+	instances map[string]endpoint.Endpoint
+}
+
+var _ InstanceSubscriber = &Union{}
+
+// NewUnion returns a Union of the subscribers.
+func NewUnion(subscribers ...Subscriber) *Union {
+	return &Union{subscribers: subscribers}
+}
+
+// Endpoints implements Subscriber. The same slice is returned as long as none
+// of the subscribers yield new endpoints, so that balancers tracking
+// per-endpoint state can be stacked on top.
+func (u *Union) Endpoints() ([]endpoint.Endpoint, error) {
+	if err := u.update(); err != nil {
+		return nil, err
+	}
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	return u.endpoints, nil
+}
+
+// Instances implements InstanceSubscriber. Endpoints of subscribers that
+// aren't InstanceSubscribers are keyed by their position, as "#i/j" for the
+// jth endpoint of the ith subscriber, counting from zero.
+func (u *Union) Instances() (map[string]endpoint.Endpoint, error) {
+	if err := u.update(); err != nil {
+		return nil, err
+	}
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	return u.instances, nil
+}
+
+func (u *Union) update() error {
+	var (
+		results   = make([]interface{}, len(u.subscribers))
+		lastErr   error
+		succeeded bool
+	)
+	for i, s := range u.subscribers {
+		var err error
+		if is, ok := s.(InstanceSubscriber); ok {
+			results[i], err = is.Instances()
+		} else {
+			results[i], err = s.Endpoints()
+		}
+		if err != nil {
+			lastErr = err
+			results[i] = nil
+		} else {
+			succeeded = true
+		}
+	}
+	if !succeeded && lastErr != nil {
+		return lastErr
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if u.endpoints != nil && sameResults(results, u.results) {
+		return nil
+	}
+
+	var (
+		endpoints = []endpoint.Endpoint{}
+		instances = map[string]endpoint.Endpoint{}
+	)
+	for i, result := range results {
+		switch r := result.(type) {
+		case map[string]endpoint.Endpoint:
+			names := make([]string, 0, len(r))
+			for name := range r {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if _, ok := instances[name]; ok {
+					continue
+				}
+				instances[name] = r[name]
+				endpoints = append(endpoints, r[name])
+			}
+		case []endpoint.Endpoint:
+			for j, e := range r {
+				instances[fmt.Sprintf("#%d/%d", i, j)] = e
+			}
+			endpoints = append(endpoints, r...)
+		}
+	}
+	u.results, u.endpoints, u.instances = results, endpoints, instances
+	return nil
+}
+
+// sameResults reports whether the subscribers yielded the same maps or slices
+// as before. Comparing their addresses is sound, as the previous results are
+// kept, so their memory can't have been reused.
+func sameResults(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] == nil && b[i] == nil {
+			continue // failed both times
+		}
+		if !identity.Same(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Failover yields the endpoints of the first of several subscribers, in order
+// of priority, that yields any without error. A secondary source, e.g. a
+// FixedSubscriber of last-resort instances, thereby takes over while the
+// primary service discovery system fails or knows no instances.
+//
+// If no subscriber yields endpoints, Failover returns the last error, or no
+// endpoints and no error if none of them failed.
+type Failover []Subscriber
+
+var _ Subscriber = Failover{}
+
+// NewFailover returns a Failover between the subscribers, the first of which
+// has the highest priority.
+func NewFailover(subscribers ...Subscriber) Failover {
+	return Failover(subscribers)
+}
+
+// Endpoints implements Subscriber.
+func (f Failover) Endpoints() ([]endpoint.Endpoint, error) {
+	var lastErr error
+	for _, s := range f {
+		endpoints, err := s.Endpoints()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(endpoints) > 0 {
+			return endpoints, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return []endpoint.Endpoint{}, nil
+}
@@ -0,0 +1,162 @@
+package sd
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestUnion(t *testing.T) {
+	var (
+		discovered = &mutableInstanceSubscriber{instances: map[string]endpoint.Endpoint{"a": named("a"), "b": named("b")}}
+		fixed      = FixedInstances(map[string]endpoint.Endpoint{"b": named("b (fixed)"), "c": named("c")})
+		anonymous  = FixedSubscriber{named("d")}
+		u          = NewUnion(discovered, fixed, anonymous)
+	)
+
+	if want, have := "a,b,c,d", names(t, u); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	first, _ := u.Endpoints()
+	second, _ := u.Endpoints()
+	if &first[0] != &second[0] {
+		t.Error("endpoints changed without a change in the subscribers")
+	}
+
+	discovered.err = errors.New("discovery is down")
+	if want, have := "b (fixed),c,d", names(t, u); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	discovered.err = nil
+	discovered.instances = map[string]endpoint.Endpoint{"e": named("e")}
+	if want, have := "b (fixed),c,d,e", names(t, u); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestUnionInstances(t *testing.T) {
+	var (
+		discovered = &mutableInstanceSubscriber{instances: map[string]endpoint.Endpoint{"a": named("a"), "b": named("b")}}
+		anonymous  = FixedSubscriber{named("c")}
+		fixed, _   = NewFixedInstanceSubscriber(func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return named(instance + " (fixed)"), nil, nil
+		}, "b", "d")
+		u = NewUnion(discovered, anonymous, fixed)
+	)
+
+	instances, err := u.Instances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for name, e := range instances {
+		response, _ := e(context.Background(), struct{}{})
+		have = append(have, name+"="+response.(string))
+	}
+	sort.Strings(have)
+	if want, have := "#1/0=c,a=a,b=b,d=d (fixed)", strings.Join(have, ","); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// A Union is an InstanceSubscriber itself, so it can be nested.
+	if want, have := "a,b,c,d (fixed)", names(t, NewUnion(u, fixed)); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestUnionFails(t *testing.T) {
+	err := errors.New("discovery is down")
+	u := NewUnion(&mutableInstanceSubscriber{err: err}, &mutableInstanceSubscriber{err: err})
+	if _, have := u.Endpoints(); err != have {
+		t.Errorf("want %v, have %v", err, have)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	var (
+		primary   = &mutableInstanceSubscriber{instances: map[string]endpoint.Endpoint{"a": named("a")}}
+		secondary = FixedSubscriber{named("fallback")}
+		f         = NewFailover(primary, secondary)
+	)
+
+	if want, have := "a", names(t, f); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	primary.err = errors.New("discovery is down")
+	if want, have := "fallback", names(t, f); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	primary.err = nil
+	primary.instances = map[string]endpoint.Endpoint{}
+	if want, have := "fallback", names(t, f); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	primary.instances = map[string]endpoint.Endpoint{"b": named("b")}
+	if want, have := "b", names(t, f); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestFailoverFails(t *testing.T) {
+	err := errors.New("discovery is down")
+	f := NewFailover(&mutableInstanceSubscriber{err: err}, FixedSubscriber{})
+	if _, have := f.Endpoints(); err != have {
+		t.Errorf("want %v, have %v", err, have)
+	}
+
+	endpoints, err := NewFailover(FixedSubscriber{}, FixedSubscriber{}).Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 0, len(endpoints); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func named(name string) endpoint.Endpoint {
+	return func(context.Context, interface{}) (interface{}, error) { return name, nil }
+}
+
+func names(t *testing.T, s Subscriber) string {
+	endpoints, err := s.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range endpoints {
+		response, _ := e(context.Background(), struct{}{})
+		names = append(names, response.(string))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+type mutableInstanceSubscriber struct {
+	instances map[string]endpoint.Endpoint
+	err       error
+}
+
+func (s *mutableInstanceSubscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return FixedInstances(s.instances).Endpoints()
+}
+
+func (s *mutableInstanceSubscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.instances, nil
+}
@@ -10,6 +10,10 @@ type Client interface {
 	// Deregister a service with the local agent.
 	Deregister(r *consul.AgentServiceRegistration) error
 
+	// UpdateTTL sets the status of a TTL check with the local agent, example:
+	// consul.HealthPassing.
+	UpdateTTL(checkID, output, status string) error
+
 	// Service
 	Service(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error)
 }
@@ -32,6 +36,10 @@ func (c *client) Deregister(r *consul.AgentServiceRegistration) error {
 	return c.consul.Agent().ServiceDeregister(r.ID)
 }
 
+func (c *client) UpdateTTL(checkID, output, status string) error {
+	return c.consul.Agent().UpdateTTL(checkID, output, status)
+}
+
 func (c *client) Service(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
 	return c.consul.Health().Service(service, tag, passingOnly, queryOpts)
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"reflect"
+	"sync"
 	"testing"
 
 	stdconsul "github.com/hashicorp/consul/api"
@@ -58,17 +59,26 @@ func TestClientRegistration(t *testing.T) {
 
 type testClient struct {
 	entries []*stdconsul.ServiceEntry
+
+	mtx     sync.Mutex
+	filters []string            // of the service queries
+	checks  map[string][]string // check ID: statuses
 }
 
 func newTestClient(entries []*stdconsul.ServiceEntry) *testClient {
 	return &testClient{
 		entries: entries,
+		checks:  map[string][]string{},
 	}
 }
 
 var _ Client = &testClient{}
 
 func (c *testClient) Service(service, tag string, _ bool, opts *stdconsul.QueryOptions) ([]*stdconsul.ServiceEntry, *stdconsul.QueryMeta, error) {
+	c.mtx.Lock()
+	c.filters = append(c.filters, opts.Filter)
+	c.mtx.Unlock()
+
 	var results []*stdconsul.ServiceEntry
 
 	for _, entry := range c.entries {
@@ -124,6 +134,25 @@ func (c *testClient) Deregister(r *stdconsul.AgentServiceRegistration) error {
 	return nil
 }
 
+func (c *testClient) UpdateTTL(checkID, output, status string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.checks[checkID] = append(c.checks[checkID], status)
+	return nil
+}
+
+func (c *testClient) lastFilter() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.filters[len(c.filters)-1]
+}
+
+func (c *testClient) checkUpdates(checkID string) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.checks[checkID])
+}
+
 func registration2entry(r *stdconsul.AgentServiceRegistration) *stdconsul.ServiceEntry {
 	return &stdconsul.ServiceEntry{
 		Node: &stdconsul.Node{
@@ -2,6 +2,8 @@ package consul
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	stdconsul "github.com/hashicorp/consul/api"
 
@@ -9,36 +11,168 @@ import (
 )
 
 // Registrar registers service instance liveness information to Consul.
+//
+// If the registration has TTL checks, the Registrar keeps them passing in the
+// background until Deregister is called. An instance that dies without
+// deregistering then fails its checks once the TTL elapses, and drops out of
+// discovery. Set DeregisterCriticalServiceAfter on the checks to have Consul
+// remove it altogether.
 type Registrar struct {
 	client       Client
 	registration *stdconsul.AgentServiceRegistration
 	logger       log.Logger
+	heartbeat    time.Duration
+	checkIDs     []string // of the TTL checks
+	quitmtx      sync.Mutex
+	quit         chan struct{}
+	done         chan struct{}
+}
+
+// RegistrarOption sets an optional parameter for registrars.
+type RegistrarOption func(*Registrar)
+
+// RegistrarHeartbeat sets the interval at which TTL checks are passed. By
+// default, or if it isn't positive, it's a third of the shortest TTL, so that
+// a check survives two missed heartbeats.
+func RegistrarHeartbeat(d time.Duration) RegistrarOption {
+	return func(p *Registrar) { p.heartbeat = d }
 }
 
 // NewRegistrar returns a Consul Registrar acting on the provided catalog
 // registration.
-func NewRegistrar(client Client, r *stdconsul.AgentServiceRegistration, logger log.Logger) *Registrar {
-	return &Registrar{
+func NewRegistrar(client Client, r *stdconsul.AgentServiceRegistration, logger log.Logger, options ...RegistrarOption) *Registrar {
+	p := &Registrar{
 		client:       client,
 		registration: r,
 		logger:       log.NewContext(logger).With("service", r.Name, "tags", fmt.Sprint(r.Tags), "address", r.Address),
 	}
+	var shortest time.Duration
+	for _, check := range ttlChecks(r) {
+		ttl, err := time.ParseDuration(check.ttl)
+		if err != nil || ttl <= 0 {
+			p.logger.Log("check", check.id, "err", fmt.Sprintf("invalid TTL %q", check.ttl))
+			continue
+		}
+		if shortest == 0 || ttl < shortest {
+			shortest = ttl
+		}
+		p.checkIDs = append(p.checkIDs, check.id)
+	}
+	for _, option := range options {
+		option(p)
+	}
+	if p.heartbeat < 0 {
+		p.logger.Log("err", fmt.Sprintf("invalid heartbeat %s", p.heartbeat))
+	}
+	if p.heartbeat <= 0 {
+		p.heartbeat = shortest / 3
+	}
+	if p.heartbeat <= 0 {
+		p.heartbeat = shortest // TTLs of a few nanoseconds; never zero with TTL checks
+	}
+	return p
 }
 
-// Register implements sd.Registrar interface.
+// Register implements sd.Registrar interface. If the registration has TTL
+// checks, they're passed right away, and then every heartbeat until
+// Deregister is called.
 func (p *Registrar) Register() {
 	if err := p.client.Register(p.registration); err != nil {
 		p.logger.Log("err", err)
 	} else {
 		p.logger.Log("action", "register")
 	}
+
+	if len(p.checkIDs) == 0 {
+		return
+	}
+	p.pass()
+
+	p.quitmtx.Lock()
+	defer p.quitmtx.Unlock()
+	if p.quit != nil {
+		return // already heartbeating
+	}
+	p.quit = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.loop(p.quit, p.done)
+}
+
+func (p *Registrar) loop(quit, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(p.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.pass()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// pass passes the TTL checks. A failure is retried on the next heartbeat.
+func (p *Registrar) pass() {
+	for _, id := range p.checkIDs {
+		if err := p.client.UpdateTTL(id, "", stdconsul.HealthPassing); err != nil {
+			p.logger.Log("check", id, "err", err, "action", "heartbeat")
+		}
+	}
 }
 
 // Deregister implements sd.Registrar interface.
 func (p *Registrar) Deregister() {
+	// Stop the heartbeat and wait for it, so that a check can't be passed
+	// after the service has been deregistered.
+	p.quitmtx.Lock()
+	if p.quit != nil {
+		close(p.quit)
+		<-p.done
+		p.quit, p.done = nil, nil
+	}
+	p.quitmtx.Unlock()
+
 	if err := p.client.Deregister(p.registration); err != nil {
 		p.logger.Log("err", err)
 	} else {
 		p.logger.Log("action", "deregister")
 	}
 }
+
+type ttlCheck struct {
+	id  string
+	ttl string
+}
+
+// ttlChecks returns the TTL checks of the registration, with the IDs the
+// agent assigns them: the given CheckID, or else "service:<service ID>",
+// suffixed with the 1-based position of the check if there are several.
+func ttlChecks(r *stdconsul.AgentServiceRegistration) []ttlCheck {
+	var checks stdconsul.AgentServiceChecks
+	if r.Check != nil {
+		checks = append(checks, r.Check)
+	}
+	checks = append(checks, r.Checks...)
+
+	serviceID := r.ID
+	if serviceID == "" {
+		serviceID = r.Name
+	}
+
+	var ttls []ttlCheck
+	for i, check := range checks {
+		if check.TTL == "" {
+			continue
+		}
+		id := check.CheckID
+		if id == "" {
+			id = "service:" + serviceID
+			if len(checks) > 1 {
+				id += fmt.Sprintf(":%d", i+1)
+			}
+		}
+		ttls = append(ttls, ttlCheck{id: id, ttl: check.TTL})
+	}
+	return ttls
+}
@@ -1,7 +1,9 @@
 package consul
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	stdconsul "github.com/hashicorp/consul/api"
 
@@ -25,3 +27,57 @@ func TestRegistrar(t *testing.T) {
 		t.Errorf("want %d, have %d", want, have)
 	}
 }
+
+func TestRegistrarTTLCheck(t *testing.T) {
+	client := newTestClient([]*stdconsul.ServiceEntry{})
+	registration := *testRegistration
+	registration.Check = &stdconsul.AgentServiceCheck{TTL: "10s"}
+	p := NewRegistrar(client, &registration, log.NewNopLogger(), RegistrarHeartbeat(time.Millisecond))
+
+	p.Register()
+	if want, have := 1, client.checkUpdates("service:my-id"); have < want {
+		t.Fatalf("want at least %d, have %d", want, have)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if want, have := 2, client.checkUpdates("service:my-id"); have < want {
+		t.Errorf("want at least %d, have %d", want, have)
+	}
+
+	p.Deregister()
+	updates := client.checkUpdates("service:my-id")
+	time.Sleep(20 * time.Millisecond)
+	if want, have := updates, client.checkUpdates("service:my-id"); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestTTLChecks(t *testing.T) {
+	registration := &stdconsul.AgentServiceRegistration{
+		Name:  "my-name",
+		Check: &stdconsul.AgentServiceCheck{TTL: "10s"},
+		Checks: stdconsul.AgentServiceChecks{
+			{HTTP: "http://localhost:8080/health", Interval: "10s"},
+			{CheckID: "my-check", TTL: "30s"},
+			{TTL: "1m"},
+		},
+	}
+	var have []string
+	for _, check := range ttlChecks(registration) {
+		have = append(have, check.id+"="+check.ttl)
+	}
+	if want := []string{"service:my-name:1=10s", "my-check=30s", "service:my-name:4=1m"}; fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	p := NewRegistrar(newTestClient(nil), registration, log.NewNopLogger())
+	if want, have := 10*time.Second/3, p.heartbeat; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	// Heartbeats that would make the ticker panic are replaced by the default.
+	for _, d := range []time.Duration{0, -time.Second} {
+		p := NewRegistrar(newTestClient(nil), registration, log.NewNopLogger(), RegistrarHeartbeat(d))
+		if want, have := 10*time.Second/3, p.heartbeat; want != have {
+			t.Errorf("%v: want %v, have %v", d, want, have)
+		}
+	}
+}
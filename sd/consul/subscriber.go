@@ -3,6 +3,8 @@ package consul
 import (
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	consul "github.com/hashicorp/consul/api"
 
@@ -15,7 +17,9 @@ import (
 const defaultIndex = 0
 
 // Subscriber yields endpoints for a service in Consul. Updates to the service
-// are watched and will update the Subscriber endpoints.
+// are watched and will update the Subscriber endpoints. Instances are
+// described by their tags, and by the zone set under the "zone" key of the
+// service's metadata, or of its node's.
 type Subscriber struct {
 	cache       *cache.Cache
 	client      Client
@@ -23,18 +27,48 @@ type Subscriber struct {
 	service     string
 	tags        []string
 	passingOnly bool
+	filterTags  bool
+	filter      string
+	cacheOpts   []cache.Option
 	endpointsc  chan []endpoint.Endpoint
 	quitc       chan struct{}
 }
 
-var _ sd.Subscriber = &Subscriber{}
+var (
+	_ sd.MetadataSubscriber  = &Subscriber{}
+	_ sd.StatusSubscriber    = &Subscriber{}
+	_ sd.NotifyingSubscriber = &Subscriber{}
+)
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberFilterTags makes the Subscriber match all of its tags in Consul,
+// using a filter expression, rather than only the first one. By default, the
+// others are matched client-side, after every instance with the first tag has
+// been transferred. Filter expressions require Consul 1.4.1 or later.
+func SubscriberFilterTags() SubscriberOption {
+	return func(s *Subscriber) { s.filterTags = true }
+}
+
+// SubscriberFilter restricts the Subscriber to the instances matching a
+// Consul filter expression, example: `Service.Meta.version == "2"`. It's
+// combined with the tags, and requires Consul 1.4.1 or later.
+// https://www.consul.io/api/features/filtering.html
+func SubscriberFilter(expr string) SubscriberOption {
+	return func(s *Subscriber) { s.filter = expr }
+}
+
+// SubscriberCacheOptions configures the endpoint cache of the Subscriber.
+func SubscriberCacheOptions(options ...cache.Option) SubscriberOption {
+	return func(s *Subscriber) { s.cacheOpts = append(s.cacheOpts, options...) }
+}
 
 // NewSubscriber returns a Consul subscriber which returns endpoints for the
 // requested service. It only returns instances for which all of the passed tags
 // are present.
-func NewSubscriber(client Client, factory sd.Factory, logger log.Logger, service string, tags []string, passingOnly bool) *Subscriber {
+func NewSubscriber(client Client, factory sd.Factory, logger log.Logger, service string, tags []string, passingOnly bool, options ...SubscriberOption) *Subscriber {
 	s := &Subscriber{
-		cache:       cache.New(factory, logger),
 		client:      client,
 		logger:      log.NewContext(logger).With("service", service, "tags", fmt.Sprint(tags)),
 		service:     service,
@@ -42,22 +76,52 @@ func NewSubscriber(client Client, factory sd.Factory, logger log.Logger, service
 		passingOnly: passingOnly,
 		quitc:       make(chan struct{}),
 	}
+	for _, option := range options {
+		option(s)
+	}
+	s.cache = cache.New(factory, s.logger, s.cacheOpts...)
 
 	instances, index, err := s.getInstances(defaultIndex, nil)
 	if err == nil {
 		s.logger.Log("instances", len(instances))
+		s.cache.UpdateInstances(instances)
 	} else {
 		s.logger.Log("err", err)
+		s.cache.SetError(err)
 	}
 
-	s.cache.Update(instances)
 	go s.loop(index)
 	return s
 }
 
 // Endpoints implements the Subscriber interface.
 func (s *Subscriber) Endpoints() ([]endpoint.Endpoint, error) {
-	return s.cache.Endpoints(), nil
+	return s.cache.Endpoints()
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (s *Subscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.cache.Instances()
+}
+
+// Metadata implements the MetadataSubscriber interface.
+func (s *Subscriber) Metadata() (map[string]sd.Instance, error) {
+	return s.cache.Metadata(), nil
+}
+
+// Status implements the StatusSubscriber interface.
+func (s *Subscriber) Status() sd.Status {
+	return s.cache.Status()
+}
+
+// Notify implements the NotifyingSubscriber interface.
+func (s *Subscriber) Notify(ch chan<- sd.Event) {
+	s.cache.Notify(ch)
+}
+
+// StopNotify implements the NotifyingSubscriber interface.
+func (s *Subscriber) StopNotify(ch chan<- sd.Event) {
+	s.cache.StopNotify(ch)
 }
 
 // Stop terminates the subscriber.
@@ -67,7 +131,7 @@ func (s *Subscriber) Stop() {
 
 func (s *Subscriber) loop(lastIndex uint64) {
 	var (
-		instances []string
+		instances []sd.Instance
 		err       error
 	)
 	for {
@@ -77,13 +141,14 @@ func (s *Subscriber) loop(lastIndex uint64) {
 			return // stopped via quitc
 		case err != nil:
 			s.logger.Log("err", err)
+			s.cache.SetError(err)
 		default:
-			s.cache.Update(instances)
+			s.cache.UpdateInstances(instances)
 		}
 	}
 }
 
-func (s *Subscriber) getInstances(lastIndex uint64, interruptc chan struct{}) ([]string, uint64, error) {
+func (s *Subscriber) getInstances(lastIndex uint64, interruptc chan struct{}) ([]sd.Instance, uint64, error) {
 	tag := ""
 	if len(s.tags) > 0 {
 		tag = s.tags[0]
@@ -93,10 +158,12 @@ func (s *Subscriber) getInstances(lastIndex uint64, interruptc chan struct{}) ([
 	// https://github.com/hashicorp/consul/issues/294
 	// Hashi suggest prepared queries, but they don't support blocking.
 	// https://www.consul.io/docs/agent/http/query.html#execute
-	// If we want blocking for efficiency, we must filter tags manually.
+	// If we want blocking for efficiency, we must filter tags manually, or
+	// with a filter expression on newer versions of Consul.
+	filter, remaining := s.filterExpression()
 
 	type response struct {
-		instances []string
+		instances []sd.Instance
 		index     uint64
 	}
 
@@ -108,13 +175,14 @@ func (s *Subscriber) getInstances(lastIndex uint64, interruptc chan struct{}) ([
 	go func() {
 		entries, meta, err := s.client.Service(s.service, tag, s.passingOnly, &consul.QueryOptions{
 			WaitIndex: lastIndex,
+			Filter:    filter,
 		})
 		if err != nil {
 			errc <- err
 			return
 		}
-		if len(s.tags) > 1 {
-			entries = filterEntries(entries, s.tags[1:]...)
+		if len(remaining) > 0 {
+			entries = filterEntries(entries, remaining...)
 		}
 		resc <- response{
 			instances: makeInstances(entries),
@@ -132,6 +200,28 @@ func (s *Subscriber) getInstances(lastIndex uint64, interruptc chan struct{}) ([
 	}
 }
 
+// filterExpression returns the filter expression to send to Consul, and the
+// tags beyond the first that remain to be filtered client-side.
+func (s *Subscriber) filterExpression() (string, []string) {
+	var (
+		terms     []string
+		remaining []string
+	)
+	if s.filter != "" {
+		terms = append(terms, "("+s.filter+")")
+	}
+	if len(s.tags) > 1 {
+		if s.filterTags {
+			for _, tag := range s.tags[1:] {
+				terms = append(terms, strconv.Quote(tag)+" in Service.Tags")
+			}
+		} else {
+			remaining = s.tags[1:]
+		}
+	}
+	return strings.Join(terms, " and "), remaining
+}
+
 func filterEntries(entries []*consul.ServiceEntry, tags ...string) []*consul.ServiceEntry {
 	var es []*consul.ServiceEntry
 
@@ -153,14 +243,22 @@ ENTRIES:
 	return es
 }
 
-func makeInstances(entries []*consul.ServiceEntry) []string {
-	instances := make([]string, len(entries))
+func makeInstances(entries []*consul.ServiceEntry) []sd.Instance {
+	instances := make([]sd.Instance, len(entries))
 	for i, entry := range entries {
 		addr := entry.Node.Address
 		if entry.Service.Address != "" {
 			addr = entry.Service.Address
 		}
-		instances[i] = fmt.Sprintf("%s:%d", addr, entry.Service.Port)
+		zone := entry.Service.Meta["zone"]
+		if zone == "" {
+			zone = entry.Node.Meta["zone"]
+		}
+		instances[i] = sd.Instance{
+			Addr: fmt.Sprintf("%s:%d", addr, entry.Service.Port),
+			Zone: zone,
+			Tags: entry.Service.Tags,
+		}
 	}
 	return instances
 }
@@ -14,11 +14,13 @@ var consulState = []*consul.ServiceEntry{
 		Node: &consul.Node{
 			Address: "10.0.0.0",
 			Node:    "app00.local",
+			Meta:    map[string]string{"zone": "us-east-1b"},
 		},
 		Service: &consul.AgentService{
 			ID:      "search-api-0",
 			Port:    8000,
 			Service: "search",
+			Meta:    map[string]string{"zone": "us-east-1a"},
 			Tags: []string{
 				"api",
 				"v1",
@@ -29,6 +31,7 @@ var consulState = []*consul.ServiceEntry{
 		Node: &consul.Node{
 			Address: "10.0.0.1",
 			Node:    "app01.local",
+			Meta:    map[string]string{"zone": "us-east-1b"},
 		},
 		Service: &consul.AgentService{
 			ID:      "search-api-1",
@@ -136,3 +139,75 @@ func TestSubscriberAddressOverride(t *testing.T) {
 		t.Errorf("want %q, have %q", want, have)
 	}
 }
+
+func TestSubscriberMetadata(t *testing.T) {
+	s := NewSubscriber(newTestClient(consulState), testFactory, log.NewNopLogger(), "search", []string{"api"}, true)
+	defer s.Stop()
+
+	metadata, err := s.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 2, len(metadata); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := true, metadata["10.0.0.1:8001"].HasTag("v2"); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	// The zone of the service takes precedence over that of its node.
+	if want, have := "us-east-1a", metadata["10.0.0.0:8000"].Zone; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "us-east-1b", metadata["10.0.0.1:8001"].Zone; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestSubscriberFilterTags(t *testing.T) {
+	client := newTestClient(consulState)
+
+	s := NewSubscriber(client, testFactory, log.NewNopLogger(), "search", []string{"api", "v2"}, true, SubscriberFilterTags())
+	defer s.Stop()
+
+	if want, have := `"v2" in Service.Tags`, client.lastFilter(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// The test client ignores filter expressions, so the tags beyond the
+	// first one are no longer matched.
+	endpoints, err := s.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(endpoints); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestSubscriberFilterExpression(t *testing.T) {
+	for _, tc := range []struct {
+		tags      []string
+		options   []SubscriberOption
+		want      string
+		remaining int
+	}{
+		{[]string{"api", "v2"}, nil, ``, 1},
+		{[]string{"api"}, []SubscriberOption{SubscriberFilterTags()}, ``, 0},
+		{[]string{"api", "v2", `"quoted"`}, []SubscriberOption{SubscriberFilterTags()}, `"v2" in Service.Tags and "\"quoted\"" in Service.Tags`, 0},
+		{[]string{"api", "v2"}, []SubscriberOption{SubscriberFilter(`Service.Meta.version == "2"`)}, `(Service.Meta.version == "2")`, 1},
+		{[]string{"api", "v2"}, []SubscriberOption{SubscriberFilter(`Service.Port > 8000`), SubscriberFilterTags()}, `(Service.Port > 8000) and "v2" in Service.Tags`, 0},
+	} {
+		s := &Subscriber{tags: tc.tags}
+		for _, option := range tc.options {
+			option(s)
+		}
+		have, remaining := s.filterExpression()
+		if tc.want != have {
+			t.Errorf("%v: want %q, have %q", tc.tags, tc.want, have)
+		}
+		if want, have := tc.remaining, len(remaining); want != have {
+			t.Errorf("%v: want %d, have %d", tc.tags, want, have)
+		}
+	}
+}
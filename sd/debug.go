@@ -0,0 +1,300 @@
+package sd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd/internal/identity"
+)
+
+// DebugHandler is an http.Handler that lists subscribers, with their current
+// instances and the state of their service discovery systems, to help answer
+// why a client calls the hosts it does. Subscribers that are used through the
+// handler, e.g. by a balancer, also have the requests to each instance
+// counted.
+//
+// The listing is plain text, or JSON if the format=json query parameter is
+// given. Mount the handler on a debug or admin port, e.g.
+//
+//	debug := sd.NewDebugHandler()
+//	http.Handle("/debug/sd", debug)
+//	balancer := lb.NewRoundRobin(debug.Register("search", subscriber))
+type DebugHandler struct {
+	mtx         sync.Mutex
+	subscribers map[string]*debugSubscriber
+}
+
+// NewDebugHandler returns a DebugHandler without any subscribers.
+func NewDebugHandler() *DebugHandler {
+	return &DebugHandler{subscribers: map[string]*debugSubscriber{}}
+}
+
+// Register lists the subscriber under the given name, typically that of the
+// service, replacing any subscriber registered under the same name. The last
+// update and error are listed if the subscriber is a StatusSubscriber.
+//
+// The returned subscriber yields the same endpoints, counting the requests
+// made to each instance. Use it in place of s to have the counts listed. It's
+// a StatusSubscriber or NotifyingSubscriber if s is.
+func (h *DebugHandler) Register(name string, s MetadataSubscriber) MetadataSubscriber {
+	d := &debugSubscriber{
+		MetadataSubscriber: s,
+		counters:           map[string]*debugCounter{},
+	}
+	h.mtx.Lock()
+	h.subscribers[name] = d
+	h.mtx.Unlock()
+
+	ss, status := s.(StatusSubscriber)
+	ns, notifying := s.(NotifyingSubscriber)
+	switch {
+	case status && notifying:
+		return struct {
+			*debugSubscriber
+			debugStatus
+			debugNotifier
+		}{d, debugStatus{ss}, debugNotifier{ns}}
+	case status:
+		return struct {
+			*debugSubscriber
+			debugStatus
+		}{d, debugStatus{ss}}
+	case notifying:
+		return struct {
+			*debugSubscriber
+			debugNotifier
+		}{d, debugNotifier{ns}}
+	default:
+		return d
+	}
+}
+
+// debugStatus forwards the StatusSubscriber interface of a registered
+// subscriber, which embedding it as a MetadataSubscriber would hide.
+type debugStatus struct{ s StatusSubscriber }
+
+func (d debugStatus) Status() Status { return d.s.Status() }
+
+// debugNotifier forwards the NotifyingSubscriber interface of a registered
+// subscriber. Events name instances, which are the same for the wrapped
+// endpoints.
+type debugNotifier struct{ s NotifyingSubscriber }
+
+func (d debugNotifier) Notify(ch chan<- Event)     { d.s.Notify(ch) }
+func (d debugNotifier) StopNotify(ch chan<- Event) { d.s.StopNotify(ch) }
+
+// Deregister removes the subscriber registered under the given name, e.g.
+// when it's stopped.
+func (h *DebugHandler) Deregister(name string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	delete(h.subscribers, name)
+}
+
+// debugReport is the listing of a single subscriber.
+type debugReport struct {
+	Name       string          `json:"name"`
+	LastUpdate *time.Time      `json:"last_update,omitempty"` // nil if unknown
+	Error      string          `json:"error,omitempty"`
+	Instances  []debugInstance `json:"instances"`
+}
+
+type debugInstance struct {
+	Addr     string   `json:"addr"`
+	Weight   int      `json:"weight,omitempty"`
+	Zone     string   `json:"zone,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Requests uint64   `json:"requests"`
+	Errors   uint64   `json:"errors"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *DebugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mtx.Lock()
+	names := make([]string, 0, len(h.subscribers))
+	for name := range h.subscribers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	subscribers := make([]*debugSubscriber, len(names))
+	for i, name := range names {
+		subscribers[i] = h.subscribers[name]
+	}
+	h.mtx.Unlock()
+
+	reports := make([]debugReport, len(names))
+	for i, name := range names {
+		reports[i] = subscribers[i].report(name)
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(reports)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, report := range reports {
+		writeReport(w, report)
+	}
+}
+
+func writeReport(w http.ResponseWriter, report debugReport) {
+	lastUpdate, lastError := "unknown", "none"
+	if report.LastUpdate != nil {
+		lastUpdate = "never"
+		if !report.LastUpdate.IsZero() {
+			lastUpdate = report.LastUpdate.Format(time.RFC3339)
+		}
+	}
+	if report.Error != "" {
+		lastError = report.Error
+	}
+	fmt.Fprintf(w, "%s\n", report.Name)
+	fmt.Fprintf(w, "  last update: %s\n", lastUpdate)
+	fmt.Fprintf(w, "  last error:  %s\n", lastError)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "  INSTANCE\tZONE\tWEIGHT\tTAGS\tREQUESTS\tERRORS\n")
+	for _, i := range report.Instances {
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%s\t%d\t%d\n", i.Addr, i.Zone, i.Weight, strings.Join(i.Tags, ","), i.Requests, i.Errors)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// debugSubscriber wraps the endpoints of a subscriber to count requests.
+type debugSubscriber struct {
+	MetadataSubscriber
+
+	mtx       sync.Mutex
+	source    map[string]endpoint.Endpoint // the endpoints were wrapped from
+	endpoints []endpoint.Endpoint
+	instances map[string]endpoint.Endpoint
+	counters  map[string]*debugCounter // by instance string
+}
+
+type debugCounter struct {
+	requests uint64
+	errors   uint64
+}
+
+// Endpoints implements the Subscriber interface. Endpoints are ordered
+// lexicographically by the corresponding instance string, and the same slice
+// is returned as long as the instances don't change.
+func (d *debugSubscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	if err := d.update(); err != nil {
+		return nil, err
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.endpoints, nil
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (d *debugSubscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	if err := d.update(); err != nil {
+		return nil, err
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.instances, nil
+}
+
+func (d *debugSubscriber) update() error {
+	instances, err := d.MetadataSubscriber.Instances()
+	if err != nil {
+		return err
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.instances != nil && identity.Same(instances, d.source) {
+		return nil
+	}
+
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Counters of instances that survive the update are kept.
+	counters := make(map[string]*debugCounter, len(names))
+	d.endpoints = make([]endpoint.Endpoint, len(names))
+	d.instances = make(map[string]endpoint.Endpoint, len(names))
+	for i, name := range names {
+		c, ok := d.counters[name]
+		if !ok {
+			c = &debugCounter{}
+		}
+		counters[name] = c
+		d.endpoints[i] = c.wrap(instances[name])
+		d.instances[name] = d.endpoints[i]
+	}
+	d.counters, d.source = counters, instances
+	return nil
+}
+
+func (c *debugCounter) wrap(e endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		atomic.AddUint64(&c.requests, 1)
+		response, err := e(ctx, request)
+		if err != nil {
+			atomic.AddUint64(&c.errors, 1)
+		}
+		return response, err
+	}
+}
+
+func (d *debugSubscriber) report(name string) debugReport {
+	report := debugReport{Name: name, Instances: []debugInstance{}}
+	if ss, ok := d.MetadataSubscriber.(StatusSubscriber); ok {
+		status := ss.Status()
+		report.LastUpdate = &status.LastUpdate
+		if status.Err != nil {
+			report.Error = status.Err.Error()
+		}
+	}
+
+	// Bring the counters in line with the current instances, so that newly
+	// discovered ones are listed even before they receive requests.
+	if err := d.update(); err != nil {
+		if report.Error == "" {
+			report.Error = err.Error()
+		}
+		return report
+	}
+	metadata, err := d.Metadata()
+	if err != nil && report.Error == "" {
+		report.Error = err.Error()
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	names := make([]string, 0, len(d.instances))
+	for name := range d.instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c, m := d.counters[name], metadata[name]
+		report.Instances = append(report.Instances, debugInstance{
+			Addr:     name,
+			Weight:   m.Weight,
+			Zone:     m.Zone,
+			Tags:     m.Tags,
+			Requests: atomic.LoadUint64(&c.requests),
+			Errors:   atomic.LoadUint64(&c.errors),
+		})
+	}
+	return report
+}
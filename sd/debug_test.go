@@ -0,0 +1,179 @@
+package sd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestDebugHandler(t *testing.T) {
+	var (
+		lastUpdate = time.Date(2016, 7, 1, 10, 0, 0, 0, time.UTC)
+		search     = &statusSubscriber{
+			mutableInstanceSubscriber: mutableInstanceSubscriber{instances: map[string]endpoint.Endpoint{"a": named("a"), "b": failing}},
+			metadata:                  map[string]Instance{"a": {Addr: "a", Zone: "us-east-1a"}},
+			status:                    Status{LastUpdate: lastUpdate, Err: errors.New("discovery is down")},
+		}
+		h = NewDebugHandler()
+		s = h.Register("search", search)
+	)
+	h.Register("fixed", &statusSubscriber{})
+
+	endpoints, err := s.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		endpoints[0](context.Background(), struct{}{})
+	}
+	endpoints[1](context.Background(), struct{}{})
+
+	var reports []debugReport
+	if err := json.Unmarshal(get(t, h, "/?format=json"), &reports); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(reports); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	report := reports[1]
+	if want, have := "search", report.Name; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := lastUpdate, report.LastUpdate; have == nil || !want.Equal(*have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "discovery is down", report.Error; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 2, len(report.Instances); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := (debugInstance{Addr: "a", Zone: "us-east-1a", Requests: 3}), report.Instances[0]; want.Addr != have.Addr || want.Zone != have.Zone || want.Requests != have.Requests || have.Errors != 0 {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	if have := report.Instances[1]; have.Requests != 1 || have.Errors != 1 {
+		t.Errorf("want 1 request and 1 error, have %+v", have)
+	}
+
+	// Counters survive changes to the other instances.
+	search.instances = map[string]endpoint.Endpoint{"a": named("a"), "c": named("c")}
+	text := string(get(t, h, "/"))
+	for _, want := range []string{
+		"search\n",
+		"last update: 2016-07-01T10:00:00Z",
+		"last error:  discovery is down",
+		"  a         us-east-1a  0       ",
+		"  c   ",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("want %q in\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "  b ") {
+		t.Errorf("want no instance b in\n%s", text)
+	}
+
+	h.Deregister("search")
+	if text := string(get(t, h, "/")); strings.Contains(text, "search") {
+		t.Errorf("want no subscriber search in\n%s", text)
+	}
+}
+
+func TestDebugHandlerForwardsInterfaces(t *testing.T) {
+	var (
+		status = Status{Err: errors.New("discovery is down")}
+		h      = NewDebugHandler()
+	)
+
+	s := h.Register("status", &statusSubscriber{status: status})
+	ss, ok := s.(StatusSubscriber)
+	if !ok {
+		t.Fatal("want StatusSubscriber, have none")
+	}
+	if want, have := status, ss.Status(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if _, ok := s.(NotifyingSubscriber); ok {
+		t.Error("want no NotifyingSubscriber, have one")
+	}
+
+	n := &notifyingSubscriber{}
+	s = h.Register("notifying", n)
+	if _, ok := s.(StatusSubscriber); ok {
+		t.Error("want no StatusSubscriber, have one")
+	}
+	ns, ok := s.(NotifyingSubscriber)
+	if !ok {
+		t.Fatal("want NotifyingSubscriber, have none")
+	}
+	ch := make(chan Event)
+	ns.Notify(ch)
+	if want, have := 1, len(n.notified); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	ns.StopNotify(ch)
+	if want, have := 0, len(n.notified); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func get(t *testing.T, h *DebugHandler, url string) []byte {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func failing(context.Context, interface{}) (interface{}, error) {
+	return nil, errors.New("failing")
+}
+
+type statusSubscriber struct {
+	mutableInstanceSubscriber
+	metadata map[string]Instance
+	status   Status
+}
+
+func (s *statusSubscriber) Metadata() (map[string]Instance, error) {
+	return s.metadata, nil
+}
+
+func (s *statusSubscriber) Status() Status {
+	return s.status
+}
+
+type notifyingSubscriber struct {
+	mutableInstanceSubscriber
+	notified map[chan<- Event]bool
+}
+
+func (s *notifyingSubscriber) Metadata() (map[string]Instance, error) {
+	return nil, nil
+}
+
+func (s *notifyingSubscriber) Notify(ch chan<- Event) {
+	if s.notified == nil {
+		s.notified = map[chan<- Event]bool{}
+	}
+	s.notified[ch] = true
+}
+
+func (s *notifyingSubscriber) StopNotify(ch chan<- Event) {
+	delete(s.notified, ch)
+}
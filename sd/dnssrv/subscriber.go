@@ -3,6 +3,8 @@ package dnssrv
 import (
 	"fmt"
 	"net"
+	"reflect"
+	"sort"
 	"time"
 
 	"github.com/go-kit/kit/endpoint"
@@ -12,12 +14,14 @@ import (
 )
 
 // Subscriber yields endpoints taken from the named DNS SRV record. The name is
-// resolved on a fixed schedule. Priorities and weights are ignored.
+// resolved on a fixed schedule. Weights and priorities are passed on as
+// instance metadata.
 type Subscriber struct {
 	name   string
 	cache  *cache.Cache
 	logger log.Logger
 	quit   chan struct{}
+	last   []sd.Instance // as of the last update of the cache, sorted
 }
 
 // NewSubscriber returns a DNS SRV subscriber.
@@ -26,8 +30,9 @@ func NewSubscriber(
 	ttl time.Duration,
 	factory sd.Factory,
 	logger log.Logger,
+	options ...cache.Option,
 ) *Subscriber {
-	return NewSubscriberDetailed(name, time.NewTicker(ttl), net.LookupSRV, factory, logger)
+	return NewSubscriberDetailed(name, time.NewTicker(ttl), net.LookupSRV, factory, logger, options...)
 }
 
 // NewSubscriberDetailed is the same as NewSubscriber, but allows users to
@@ -39,21 +44,23 @@ func NewSubscriberDetailed(
 	lookup Lookup,
 	factory sd.Factory,
 	logger log.Logger,
+	options ...cache.Option,
 ) *Subscriber {
+	logger = log.NewContext(logger).With("name", name)
 	p := &Subscriber{
 		name:   name,
-		cache:  cache.New(factory, logger),
+		cache:  cache.New(factory, logger, options...),
 		logger: logger,
 		quit:   make(chan struct{}),
 	}
 
 	instances, err := p.resolve(lookup)
 	if err == nil {
-		logger.Log("name", name, "instances", len(instances))
+		p.update(instances)
 	} else {
-		logger.Log("name", name, "err", err)
+		logger.Log("err", err)
+		p.cache.SetError(err)
 	}
-	p.cache.Update(instances)
 
 	go p.loop(refresh, lookup)
 	return p
@@ -71,10 +78,11 @@ func (p *Subscriber) loop(t *time.Ticker, lookup Lookup) {
 		case <-t.C:
 			instances, err := p.resolve(lookup)
 			if err != nil {
-				p.logger.Log("name", p.name, "err", err)
+				p.logger.Log("err", err)
+				p.cache.SetError(err)
 				continue // don't replace potentially-good with bad
 			}
-			p.cache.Update(instances)
+			p.update(instances)
 
 		case <-p.quit:
 			return
@@ -84,17 +92,65 @@ func (p *Subscriber) loop(t *time.Ticker, lookup Lookup) {
 
 // Endpoints implements the Subscriber interface.
 func (p *Subscriber) Endpoints() ([]endpoint.Endpoint, error) {
-	return p.cache.Endpoints(), nil
+	return p.cache.Endpoints()
 }
 
-func (p *Subscriber) resolve(lookup Lookup) ([]string, error) {
+// Instances implements the InstanceSubscriber interface.
+func (p *Subscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return p.cache.Instances()
+}
+
+// Metadata implements the MetadataSubscriber interface.
+func (p *Subscriber) Metadata() (map[string]sd.Instance, error) {
+	return p.cache.Metadata(), nil
+}
+
+// Status implements the StatusSubscriber interface.
+func (p *Subscriber) Status() sd.Status {
+	return p.cache.Status()
+}
+
+// Notify implements the NotifyingSubscriber interface.
+func (p *Subscriber) Notify(ch chan<- sd.Event) {
+	p.cache.Notify(ch)
+}
+
+// StopNotify implements the NotifyingSubscriber interface.
+func (p *Subscriber) StopNotify(ch chan<- sd.Event) {
+	p.cache.StopNotify(ch)
+}
+
+// update passes the instances on to the cache. Resolutions that change
+// nothing are skipped, so that consumers of the cache don't start over,
+// unless the cache has to be told that DNS is reachable again.
+func (p *Subscriber) update(instances []sd.Instance) {
+	sort.Sort(byAddr(instances)) // records are served in any order
+	if p.last != nil && reflect.DeepEqual(instances, p.last) && p.cache.Status().Err == nil {
+		return
+	}
+	p.logger.Log("instances", len(instances))
+	p.cache.UpdateInstances(instances)
+	p.last = instances
+}
+
+func (p *Subscriber) resolve(lookup Lookup) ([]sd.Instance, error) {
 	_, addrs, err := lookup("", "", p.name)
 	if err != nil {
-		return []string{}, err
+		return []sd.Instance{}, err
 	}
-	instances := make([]string, len(addrs))
+	instances := make([]sd.Instance, len(addrs))
 	for i, addr := range addrs {
-		instances[i] = net.JoinHostPort(addr.Target, fmt.Sprint(addr.Port))
+		instances[i] = sd.Instance{
+			Addr:     net.JoinHostPort(addr.Target, fmt.Sprint(addr.Port)),
+			Weight:   int(addr.Weight),
+			Priority: int(addr.Priority),
+		}
 	}
 	return instances, nil
 }
+
+type byAddr []sd.Instance
+
+func (a byAddr) Len() int           { return len(a) }
+func (a byAddr) Less(i, j int) bool { return a[i].Addr < a[j].Addr }
+func (a byAddr) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
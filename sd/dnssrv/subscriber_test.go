@@ -80,6 +80,65 @@ func TestRefresh(t *testing.T) {
 	}
 }
 
+func TestWeights(t *testing.T) {
+	ticker := time.NewTicker(time.Second)
+	ticker.Stop()
+
+	lookup := func(service, proto, name string) (string, []*net.SRV, error) {
+		return "cname", []*net.SRV{
+			{Target: "1.0.0.1", Port: 1001, Weight: 10, Priority: 2},
+			{Target: "1.0.0.2", Port: 1002},
+		}, nil
+	}
+	factory := func(string) (endpoint.Endpoint, io.Closer, error) { return endpoint.Nop, nil, nil }
+
+	subscriber := NewSubscriberDetailed("some.service.internal", ticker, lookup, factory, log.NewNopLogger())
+	defer subscriber.Stop()
+
+	metadata, err := subscriber.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 10, metadata["1.0.0.1:1001"].EffectiveWeight(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := 1, metadata["1.0.0.2:1002"].EffectiveWeight(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := 2, metadata["1.0.0.1:1001"].Priority; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestRefreshUnchanged(t *testing.T) {
+	ticker := time.NewTicker(time.Second)
+	ticker.Stop()
+	tickc := make(chan time.Time)
+	ticker.C = tickc
+
+	var lookups uint64
+	lookup := func(service, proto, name string) (string, []*net.SRV, error) {
+		// Records come back in a different order every time.
+		if atomic.AddUint64(&lookups, 1)%2 == 0 {
+			return "cname", []*net.SRV{{Target: "1.0.0.2", Port: 1002}, {Target: "1.0.0.1", Port: 1001}}, nil
+		}
+		return "cname", []*net.SRV{{Target: "1.0.0.1", Port: 1001}, {Target: "1.0.0.2", Port: 1002}}, nil
+	}
+	factory := func(string) (endpoint.Endpoint, io.Closer, error) { return endpoint.Nop, nil, nil }
+
+	subscriber := NewSubscriberDetailed("some.service.internal", ticker, lookup, factory, log.NewNopLogger())
+	defer subscriber.Stop()
+	lastUpdate := subscriber.Status().LastUpdate
+
+	// Every tick is received once the previous one has been handled.
+	for i := 0; i < 3; i++ {
+		tickc <- time.Now()
+	}
+	if want, have := lastUpdate, subscriber.Status().LastUpdate; !want.Equal(have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
 type nopCloser struct{}
 
 func (nopCloser) Close() error { return nil }
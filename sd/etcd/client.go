@@ -3,6 +3,7 @@ package etcd
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -12,6 +13,14 @@ import (
 	"golang.org/x/net/context"
 )
 
+var (
+	// ErrNoKey indicates a client method needs a key but receives none.
+	ErrNoKey = errors.New("no key provided")
+
+	// ErrNoValue indicates a client method needs a value but receives none.
+	ErrNoValue = errors.New("no value provided")
+)
+
 // Client is a wrapper around the etcd client.
 type Client interface {
 	// GetEntries will query the given prefix in etcd and returns a set of entries.
@@ -20,11 +29,22 @@ type Client interface {
 	// WatchPrefix starts watching every change for given prefix in etcd. When an
 	// change is detected it will populate the responseChan when an *etcd.Response.
 	WatchPrefix(prefix string, responseChan chan *etcd.Response)
+
+	// Register a service with etcd. Registering an existing service again
+	// resets its TTL, and overwrites the key if its value has changed.
+	Register(s Service) error
+
+	// Deregister a service with etcd.
+	Deregister(s Service) error
+
+	// Close stops all watches, and releases the resources of the client.
+	Close()
 }
 
 type client struct {
 	keysAPI etcd.KeysAPI
 	ctx     context.Context
+	cancel  context.CancelFunc
 }
 
 // ClientOptions defines options for the etcd client.
@@ -101,7 +121,8 @@ func NewClient(ctx context.Context, machines []string, options ClientOptions) (C
 		c = etcd.NewKeysAPI(ce)
 	}
 
-	return &client{c, ctx}, nil
+	ctx, cancel := context.WithCancel(ctx)
+	return &client{keysAPI: c, ctx: ctx, cancel: cancel}, nil
 }
 
 // GetEntries implements the etcd Client interface.
@@ -129,3 +150,46 @@ func (c *client) WatchPrefix(prefix string, responseChan chan *etcd.Response) {
 		responseChan <- res
 	}
 }
+
+// Register implements the etcd Client interface.
+func (c *client) Register(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+	if s.Value == "" {
+		return ErrNoValue
+	}
+	if s.TTL == nil {
+		_, err := c.keysAPI.Set(c.ctx, s.Key, s.Value, nil)
+		return err
+	}
+
+	// Refresh the TTL of a key that's already registered with the same value.
+	// Unlike a full write, a refresh doesn't notify watchers, so heartbeats
+	// don't make every subscriber fetch the service again.
+	_, err := c.keysAPI.Set(c.ctx, s.Key, "", &etcd.SetOptions{
+		TTL:       s.TTL.ttl,
+		Refresh:   true,
+		PrevExist: etcd.PrevExist,
+		PrevValue: s.Value,
+	})
+	if e, ok := err.(etcd.Error); ok && (e.Code == etcd.ErrorCodeKeyNotFound || e.Code == etcd.ErrorCodeTestFailed) {
+		// The key expired, was never written, or holds another value.
+		_, err = c.keysAPI.Set(c.ctx, s.Key, s.Value, &etcd.SetOptions{TTL: s.TTL.ttl})
+	}
+	return err
+}
+
+// Deregister implements the etcd Client interface.
+func (c *client) Deregister(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+	_, err := c.keysAPI.Delete(c.ctx, s.Key, nil)
+	return err
+}
+
+// Close implements the etcd Client interface.
+func (c *client) Close() {
+	c.cancel()
+}
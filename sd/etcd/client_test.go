@@ -0,0 +1,98 @@
+package etcd
+
+import (
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+	"golang.org/x/net/context"
+)
+
+func TestClientRegisterRefreshes(t *testing.T) {
+	keys := &fakeKeysAPI{values: map[string]string{}}
+	c := &client{keysAPI: keys, ctx: context.Background()}
+	s := Service{Key: "/foo/1", Value: "1:1", TTL: NewTTLOption(time.Second, 10*time.Second)}
+
+	// The first registration finds no key to refresh, and writes it.
+	if err := c.Register(s); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1:1", keys.values["/foo/1"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 1, keys.writes; want != have {
+		t.Errorf("want %d writes, have %d", want, have)
+	}
+
+	// Heartbeats only refresh the TTL.
+	for i := 0; i < 3; i++ {
+		if err := c.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want, have := 1, keys.writes; want != have {
+		t.Errorf("want %d writes, have %d", want, have)
+	}
+	if want, have := 3, keys.refreshes; want != have {
+		t.Errorf("want %d refreshes, have %d", want, have)
+	}
+
+	// A new value is written in full.
+	s.Value = "1:2"
+	if err := c.Register(s); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1:2", keys.values["/foo/1"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 2, keys.writes; want != have {
+		t.Errorf("want %d writes, have %d", want, have)
+	}
+}
+
+func TestClientRegisterWithoutTTL(t *testing.T) {
+	keys := &fakeKeysAPI{values: map[string]string{}}
+	c := &client{keysAPI: keys, ctx: context.Background()}
+	s := Service{Key: "/foo/1", Value: "1:1"}
+
+	for i := 0; i < 2; i++ {
+		if err := c.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want, have := 2, keys.writes; want != have {
+		t.Errorf("want %d writes, have %d", want, have)
+	}
+	if want, have := 0, keys.refreshes; want != have {
+		t.Errorf("want %d refreshes, have %d", want, have)
+	}
+}
+
+// fakeKeysAPI implements just enough of the etcd v2 keys API to register
+// services, following the semantics of the etcd server.
+type fakeKeysAPI struct {
+	etcd.KeysAPI
+	values    map[string]string
+	writes    int
+	refreshes int
+}
+
+func (k *fakeKeysAPI) Set(_ context.Context, key, value string, opts *etcd.SetOptions) (*etcd.Response, error) {
+	if opts == nil {
+		opts = &etcd.SetOptions{}
+	}
+	prev, ok := k.values[key]
+	if opts.PrevExist == etcd.PrevExist && !ok {
+		return nil, etcd.Error{Code: etcd.ErrorCodeKeyNotFound, Message: "Key not found"}
+	}
+	if opts.PrevValue != "" && opts.PrevValue != prev {
+		return nil, etcd.Error{Code: etcd.ErrorCodeTestFailed, Message: "Compare failed"}
+	}
+	if opts.Refresh {
+		k.refreshes++
+		return &etcd.Response{Action: "update", Node: &etcd.Node{Key: key, Value: prev}}, nil
+	}
+	k.writes++
+	k.values[key] = value
+	return &etcd.Response{Action: "set", Node: &etcd.Node{Key: key, Value: value}}, nil
+}
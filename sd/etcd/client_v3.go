@@ -0,0 +1,256 @@
+package etcd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+type clientV3 struct {
+	kv            clientv3.KV
+	watcher       clientv3.Watcher
+	lease         clientv3.Lease
+	ctx           context.Context
+	cancel        context.CancelFunc
+	retryInterval time.Duration // between failed watches
+
+	mtx    sync.Mutex
+	leases map[string]clientv3.LeaseID // by key
+}
+
+// NewClientV3 returns a Client backed by the etcd v3 API, with a connection
+// to the named machines. Unlike NewClient, machines may be given with or
+// without schema, e.g. "localhost:2379". Only the TLS options and
+// DialTimeout apply.
+//
+// The v3 API has no directories, so every prefix passed to the client is
+// treated as one: "/foo" matches "/foo/1", but not "/foobar". Registrations
+// with a TTL are attached to a lease, which is kept alive by every subsequent
+// Register call, and revoked by Deregister.
+//
+// The connection is closed when the context is canceled, or when the client
+// is closed.
+func NewClientV3(ctx context.Context, machines []string, options ClientOptions) (Client, error) {
+	cfg := clientv3.Config{
+		Endpoints:   machines,
+		DialTimeout: options.DialTimeout,
+	}
+	if options.Cert != "" && options.Key != "" {
+		tlsCert, err := tls.LoadX509KeyPair(options.Cert, options.Key)
+		if err != nil {
+			return nil, err
+		}
+		caCertCt, err := ioutil.ReadFile(options.CaCert)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCertCt)
+		cfg.TLS = &tls.Config{
+			Certificates: []tls.Certificate{tlsCert},
+			RootCAs:      caCertPool,
+		}
+	}
+
+	c, err := clientv3.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		<-ctx.Done()
+		c.Close()
+	}()
+
+	return &clientV3{
+		kv:            clientv3.NewKV(c),
+		watcher:       clientv3.NewWatcher(c),
+		lease:         clientv3.NewLease(c),
+		ctx:           ctx,
+		cancel:        cancel,
+		retryInterval: time.Second,
+		leases:        map[string]clientv3.LeaseID{},
+	}, nil
+}
+
+// GetEntries implements the etcd Client interface.
+func (c *clientV3) GetEntries(prefix string) ([]string, error) {
+	resp, err := c.kv.Get(c.ctx, directory(prefix), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]string, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		entries[i] = string(kv.Value)
+	}
+	return entries, nil
+}
+
+// WatchPrefix implements the etcd Client interface. Changes are reported as
+// v2 responses, so that the Subscriber works with either version of the API.
+//
+// The watch lasts until the client is closed. If it fails, it's resumed from
+// the revision after the last change seen. If changes were lost because that
+// revision has been compacted, a response with the action "resync" is sent,
+// so that the caller fetches the entries again.
+func (c *clientV3) WatchPrefix(prefix string, responseChan chan *etcd.Response) {
+	var rev int64 // of the last change seen, or to resume after
+	for {
+		err := c.watch(directory(prefix), &rev, responseChan)
+		if c.ctx.Err() != nil {
+			return
+		}
+		if err == errCompacted {
+			if !c.send(responseChan, &etcd.Response{Action: "resync", Node: &etcd.Node{Key: directory(prefix)}}) {
+				return
+			}
+			continue
+		}
+		// The watch was canceled by the server, or the connection failed.
+		select {
+		case <-time.After(c.retryInterval):
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+var errCompacted = errors.New("required revision has been compacted")
+
+// watch relays the changes below the directory after revision *rev, until
+// the watch fails, updating *rev as it goes.
+func (c *clientV3) watch(dir string, rev *int64, responseChan chan *etcd.Response) error {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+
+	opts := []clientv3.OpOption{clientv3.WithPrefix()}
+	if *rev > 0 {
+		opts = append(opts, clientv3.WithRev(*rev+1))
+	}
+	for wresp := range c.watcher.Watch(ctx, dir, opts...) {
+		if wresp.CompactRevision != 0 {
+			*rev = wresp.CompactRevision - 1
+			return errCompacted
+		}
+		if err := wresp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range wresp.Events {
+			action := "set"
+			if ev.Type == clientv3.EventTypeDelete {
+				action = "delete"
+			}
+			if !c.send(responseChan, &etcd.Response{
+				Action: action,
+				Node: &etcd.Node{
+					Key:   string(ev.Kv.Key),
+					Value: string(ev.Kv.Value),
+				},
+			}) {
+				return c.ctx.Err()
+			}
+			*rev = ev.Kv.ModRevision
+		}
+	}
+	return errors.New("watch closed")
+}
+
+// send delivers the response, unless the client is closed first.
+func (c *clientV3) send(responseChan chan *etcd.Response, resp *etcd.Response) bool {
+	select {
+	case responseChan <- resp:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// Register implements the etcd Client interface. With a TTL, the first call
+// writes the key under a new lease, and later calls only keep that lease
+// alive. If the lease has expired in the meantime, the key is written again
+// under a new one.
+func (c *clientV3) Register(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+	if s.Value == "" {
+		return ErrNoValue
+	}
+	if s.TTL == nil {
+		_, err := c.kv.Put(c.ctx, s.Key, s.Value)
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if id, ok := c.leases[s.Key]; ok {
+		if _, err := c.lease.KeepAliveOnce(c.ctx, id); err == nil {
+			return nil
+		}
+		delete(c.leases, s.Key)
+	}
+
+	grant, err := c.lease.Grant(c.ctx, ttlSeconds(s.TTL.ttl))
+	if err != nil {
+		return err
+	}
+	if _, err := c.kv.Put(c.ctx, s.Key, s.Value, clientv3.WithLease(grant.ID)); err != nil {
+		return err
+	}
+	c.leases[s.Key] = grant.ID
+	return nil
+}
+
+// Deregister implements the etcd Client interface.
+func (c *clientV3) Deregister(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+	if _, err := c.kv.Delete(c.ctx, s.Key); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if id, ok := c.leases[s.Key]; ok {
+		delete(c.leases, s.Key)
+		if _, err := c.lease.Revoke(c.ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements the etcd Client interface. It stops all watches, and closes
+// the connection.
+func (c *clientV3) Close() {
+	c.cancel()
+}
+
+// directory makes sure a prefix only matches keys below it.
+func directory(prefix string) string {
+	if strings.HasSuffix(prefix, "/") {
+		return prefix
+	}
+	return prefix + "/"
+}
+
+// ttlSeconds converts a TTL to the whole seconds leases are granted in,
+// rounding up so that a lease never expires earlier than asked for.
+func ttlSeconds(ttl time.Duration) int64 {
+	n := int64(ttl / time.Second)
+	if ttl%time.Second != 0 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,244 @@
+package etcd
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+func TestDirectory(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"/foo":  "/foo/",
+		"/foo/": "/foo/",
+		"/":     "/",
+	} {
+		if have := directory(prefix); want != have {
+			t.Errorf("%q: want %q, have %q", prefix, want, have)
+		}
+	}
+}
+
+func TestTTLSeconds(t *testing.T) {
+	for ttl, want := range map[time.Duration]int64{
+		10 * time.Second:         10,
+		10500 * time.Millisecond: 11,
+		time.Millisecond:         1,
+	} {
+		if have := ttlSeconds(ttl); want != have {
+			t.Errorf("%s: want %d, have %d", ttl, want, have)
+		}
+	}
+}
+
+func TestClientV3Register(t *testing.T) {
+	c, kv, lease, _ := newTestClientV3()
+	defer c.Close()
+	s := Service{Key: "/foo/1", Value: "1:1", TTL: NewTTLOption(time.Second, 10*time.Second)}
+
+	// The first registration writes the key under a new lease.
+	if err := c.Register(s); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1:1", kv.values["/foo/1"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := int64(10), lease.ttls[1]; want != have {
+		t.Errorf("want TTL %d, have %d", want, have)
+	}
+
+	// Later ones only keep the lease alive.
+	if err := c.Register(s); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, kv.puts; want != have {
+		t.Errorf("want %d puts, have %d", want, have)
+	}
+	if want, have := 1, lease.keepAlives; want != have {
+		t.Errorf("want %d keepalives, have %d", want, have)
+	}
+
+	// If the lease has expired, a new one is granted, and the key written
+	// again.
+	delete(lease.ttls, 1)
+	delete(kv.values, "/foo/1")
+	if err := c.Register(s); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1:1", kv.values["/foo/1"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if _, ok := lease.ttls[2]; !ok {
+		t.Errorf("want lease 2 granted, have %v", lease.ttls)
+	}
+
+	// Deregistering deletes the key, and revokes the lease.
+	if err := c.Deregister(s); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := kv.values["/foo/1"]; ok {
+		t.Error("still registered after Deregister")
+	}
+	if _, ok := lease.ttls[2]; ok {
+		t.Error("lease not revoked by Deregister")
+	}
+}
+
+func TestClientV3WatchPrefix(t *testing.T) {
+	c, _, _, watcher := newTestClientV3()
+	responses := make(chan *etcd.Response)
+	go c.WatchPrefix("/foo", responses)
+	defer c.Close()
+
+	wch := watcher.next(t)
+	wch <- clientv3.WatchResponse{Events: []*clientv3.Event{
+		{Type: clientv3.EventTypePut, Kv: &clientv3.KeyValue{Key: []byte("/foo/1"), Value: []byte("1:1"), ModRevision: 4}},
+		{Type: clientv3.EventTypeDelete, Kv: &clientv3.KeyValue{Key: []byte("/foo/2"), ModRevision: 5}},
+	}}
+	assertResponse(t, responses, "set", "/foo/1", "1:1")
+	assertResponse(t, responses, "delete", "/foo/2", "")
+
+	// The watch is canceled by the server, and resumed.
+	wch <- clientv3.WatchResponse{Canceled: true}
+	wch = watcher.next(t)
+	if want, have := 2, watcher.lastOptions(); want != have {
+		t.Errorf("want %d options, have %d", want, have)
+	}
+	wch <- clientv3.WatchResponse{Events: []*clientv3.Event{
+		{Type: clientv3.EventTypePut, Kv: &clientv3.KeyValue{Key: []byte("/foo/3"), Value: []byte("3:3"), ModRevision: 6}},
+	}}
+	assertResponse(t, responses, "set", "/foo/3", "3:3")
+
+	// The revision to resume from is compacted: the caller should fetch the
+	// entries again, and the watch resumes from the compacted revision.
+	wch <- clientv3.WatchResponse{CompactRevision: 9}
+	assertResponse(t, responses, "resync", "/foo/", "")
+	watcher.next(t)
+}
+
+func assertResponse(t *testing.T, responses chan *etcd.Response, action, key, value string) {
+	select {
+	case resp := <-responses:
+		if want, have := action+" "+key+" "+value, resp.Action+" "+resp.Node.Key+" "+resp.Node.Value; want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("want %s %s, have no response", action, key)
+	}
+}
+
+func newTestClientV3() (*clientV3, *fakeKV, *fakeLease, *fakeWatcher) {
+	var (
+		kv      = &fakeKV{values: map[string]string{}}
+		lease   = &fakeLease{ttls: map[clientv3.LeaseID]int64{}}
+		watcher = &fakeWatcher{watches: make(chan chan clientv3.WatchResponse)}
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &clientV3{
+		kv:            kv,
+		watcher:       watcher,
+		lease:         lease,
+		ctx:           ctx,
+		cancel:        cancel,
+		retryInterval: time.Millisecond,
+		leases:        map[string]clientv3.LeaseID{},
+	}
+	return c, kv, lease, watcher
+}
+
+// fakeKV implements the parts of the etcd v3 KV API used by the client.
+type fakeKV struct {
+	clientv3.KV
+	values map[string]string
+	puts   int
+}
+
+func (kv *fakeKV) Put(_ context.Context, key, val string, _ ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	kv.puts++
+	kv.values[key] = val
+	return &clientv3.PutResponse{}, nil
+}
+
+func (kv *fakeKV) Get(_ context.Context, key string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (kv *fakeKV) Delete(_ context.Context, key string, _ ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	delete(kv.values, key)
+	return &clientv3.DeleteResponse{}, nil
+}
+
+// fakeLease implements the parts of the etcd v3 Lease API used by the
+// client.
+type fakeLease struct {
+	clientv3.Lease
+	ttls       map[clientv3.LeaseID]int64 // of live leases
+	last       clientv3.LeaseID
+	keepAlives int
+}
+
+func (l *fakeLease) Grant(_ context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	l.last++
+	l.ttls[l.last] = ttl
+	return &clientv3.LeaseGrantResponse{ID: l.last, TTL: ttl}, nil
+}
+
+func (l *fakeLease) Revoke(_ context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	delete(l.ttls, id)
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+func (l *fakeLease) KeepAliveOnce(_ context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error) {
+	if _, ok := l.ttls[id]; !ok {
+		return nil, errors.New("etcdserver: requested lease not found")
+	}
+	l.keepAlives++
+	return &clientv3.LeaseKeepAliveResponse{}, nil
+}
+
+// fakeWatcher hands the channel of every watch to the test, and closes it
+// when the watch is canceled.
+type fakeWatcher struct {
+	clientv3.Watcher
+	watches chan chan clientv3.WatchResponse
+
+	mtx     sync.Mutex
+	options int // of the last watch
+}
+
+func (w *fakeWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	ch := make(chan clientv3.WatchResponse)
+	w.mtx.Lock()
+	w.options = len(opts)
+	w.mtx.Unlock()
+	select {
+	case w.watches <- ch:
+		go func() {
+			<-ctx.Done()
+			close(ch)
+		}()
+	case <-ctx.Done():
+		close(ch)
+	}
+	return ch
+}
+
+func (w *fakeWatcher) next(t *testing.T) chan<- clientv3.WatchResponse {
+	select {
+	case ch := <-w.watches:
+		return ch
+	case <-time.After(time.Second):
+		t.Fatal("want a watch, have none")
+		return nil
+	}
+}
+
+func (w *fakeWatcher) lastOptions() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.options
+}
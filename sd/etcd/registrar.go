@@ -0,0 +1,133 @@
+package etcd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	// DefaultHeartbeat is the default interval at which a registration with a
+	// TTL is refreshed.
+	DefaultHeartbeat = 3 * time.Second
+	// DefaultTTL is the default time a registration survives without being
+	// refreshed.
+	DefaultTTL = 10 * time.Second
+)
+
+// Registrar registers service instance liveness information to etcd.
+type Registrar struct {
+	client  Client
+	service Service
+	logger  log.Logger
+	quitmtx sync.Mutex
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+// Service holds the instance identifying data you want to publish to etcd.
+// The TTL field is optional; without it, the key is written once and lives
+// until it's deregistered.
+type Service struct {
+	Key   string // unique key, example: /myorganization/addsvc/10.0.2.10:80
+	Value string // returned to subscribers, example: 10.0.2.10:80
+	TTL   *TTLOption
+}
+
+// TTLOption makes a registration expire unless it's refreshed. The Registrar
+// refreshes it every heartbeat until Deregister is called, so the key
+// disappears shortly after the instance dies without deregistering.
+type TTLOption struct {
+	heartbeat time.Duration // example: 3 * time.Second
+	ttl       time.Duration // example: 10 * time.Second
+}
+
+// NewTTLOption returns a TTLOption with the given heartbeat and TTL. The
+// heartbeat should be comfortably shorter than the TTL. Zero values are
+// replaced by DefaultHeartbeat and DefaultTTL.
+func NewTTLOption(heartbeat, ttl time.Duration) *TTLOption {
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &TTLOption{
+		heartbeat: heartbeat,
+		ttl:       ttl,
+	}
+}
+
+// NewRegistrar returns an etcd Registrar acting on the provided catalog
+// registration.
+func NewRegistrar(client Client, service Service, logger log.Logger) *Registrar {
+	return &Registrar{
+		client:  client,
+		service: service,
+		logger: log.NewContext(logger).With(
+			"key", service.Key,
+			"value", service.Value,
+		),
+	}
+}
+
+// Register implements sd.Registrar interface. If the service has a TTL, the
+// registration is refreshed in the background until Deregister is called.
+func (r *Registrar) Register() {
+	if err := r.client.Register(r.service); err != nil {
+		r.logger.Log("err", err)
+	} else {
+		r.logger.Log("action", "register")
+	}
+
+	if r.service.TTL == nil {
+		return
+	}
+
+	r.quitmtx.Lock()
+	defer r.quitmtx.Unlock()
+	if r.quit != nil {
+		return // already heartbeating
+	}
+	r.quit = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.loop(r.quit, r.done)
+}
+
+func (r *Registrar) loop(quit, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.service.TTL.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// A failed refresh is retried on the next heartbeat. If the key
+			// expired meanwhile, it's simply written again.
+			if err := r.client.Register(r.service); err != nil {
+				r.logger.Log("err", err, "action", "heartbeat")
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// Deregister implements sd.Registrar interface.
+func (r *Registrar) Deregister() {
+	// Stop the heartbeat and wait for it, so that a refresh in flight can't
+	// write the key back after it's been deleted.
+	r.quitmtx.Lock()
+	if r.quit != nil {
+		close(r.quit)
+		<-r.done
+		r.quit, r.done = nil, nil
+	}
+	r.quitmtx.Unlock()
+
+	if err := r.client.Deregister(r.service); err != nil {
+		r.logger.Log("err", err)
+	} else {
+		r.logger.Log("action", "deregister")
+	}
+}
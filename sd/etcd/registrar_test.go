@@ -0,0 +1,106 @@
+package etcd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRegistrar(t *testing.T) {
+	client := &fakeClient{}
+	r := NewRegistrar(client, Service{Key: "/foo/1", Value: "1:1"}, log.NewNopLogger())
+	if _, ok := client.value("/foo/1"); ok {
+		t.Fatal("registered before Register")
+	}
+
+	r.Register()
+	if want, have := "1:1", mustValue(t, client, "/foo/1"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	r.Deregister()
+	if _, ok := client.value("/foo/1"); ok {
+		t.Error("still registered after Deregister")
+	}
+}
+
+func TestRegistrarHeartbeat(t *testing.T) {
+	client := &fakeClient{}
+	service := Service{
+		Key:   "/foo/1",
+		Value: "1:1",
+		TTL:   NewTTLOption(time.Millisecond, 10*time.Millisecond),
+	}
+	r := NewRegistrar(client, service, log.NewNopLogger())
+
+	r.Register()
+	r.Register() // must not start a second heartbeat
+	deadline := time.Now().Add(time.Second)
+	for client.registerCount() < 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("want at least 5 registrations, have %d", client.registerCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	r.Deregister()
+	if _, ok := client.value("/foo/1"); ok {
+		t.Error("still registered after Deregister")
+	}
+	n := client.registerCount()
+	time.Sleep(20 * time.Millisecond)
+	if want, have := n, client.registerCount(); want != have {
+		t.Errorf("heartbeat continued after Deregister: want %d registrations, have %d", want, have)
+	}
+}
+
+func TestRegistrarHeartbeatRecovers(t *testing.T) {
+	client := &fakeClient{err: errors.New("etcd unavailable")}
+	service := Service{
+		Key:   "/foo/1",
+		Value: "1:1",
+		TTL:   NewTTLOption(time.Millisecond, 10*time.Millisecond),
+	}
+	r := NewRegistrar(client, service, log.NewNopLogger())
+	defer r.Deregister()
+
+	r.Register()
+	if _, ok := client.value("/foo/1"); ok {
+		t.Fatal("registered despite error")
+	}
+
+	client.mtx.Lock()
+	client.err = nil
+	client.mtx.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := client.value("/foo/1"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("heartbeat didn't register the service")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNewTTLOptionDefaults(t *testing.T) {
+	o := NewTTLOption(0, 0)
+	if want, have := DefaultHeartbeat, o.heartbeat; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := DefaultTTL, o.ttl; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func mustValue(t *testing.T, c *fakeClient, key string) string {
+	v, ok := c.value(key)
+	if !ok {
+		t.Fatalf("%s not registered", key)
+	}
+	return v
+}
@@ -19,15 +19,20 @@ type Subscriber struct {
 	quitc  chan struct{}
 }
 
-var _ sd.Subscriber = &Subscriber{}
+var (
+	_ sd.MetadataSubscriber  = &Subscriber{}
+	_ sd.StatusSubscriber    = &Subscriber{}
+	_ sd.NotifyingSubscriber = &Subscriber{}
+)
 
 // NewSubscriber returns an etcd subscriber. It will start watching the given
-// prefix for changes, and update the endpoints.
-func NewSubscriber(c Client, prefix string, factory sd.Factory, logger log.Logger) (*Subscriber, error) {
+// prefix for changes, and update the endpoints. The options configure the
+// endpoint cache.
+func NewSubscriber(c Client, prefix string, factory sd.Factory, logger log.Logger, options ...cache.Option) (*Subscriber, error) {
 	s := &Subscriber{
 		client: c,
 		prefix: prefix,
-		cache:  cache.New(factory, logger),
+		cache:  cache.New(factory, logger, options...),
 		logger: logger,
 		quitc:  make(chan struct{}),
 	}
@@ -35,10 +40,11 @@ func NewSubscriber(c Client, prefix string, factory sd.Factory, logger log.Logge
 	instances, err := s.client.GetEntries(s.prefix)
 	if err == nil {
 		logger.Log("prefix", s.prefix, "instances", len(instances))
+		s.cache.Update(instances)
 	} else {
 		logger.Log("prefix", s.prefix, "err", err)
+		s.cache.SetError(err)
 	}
-	s.cache.Update(instances)
 
 	go s.loop()
 	return s, nil
@@ -53,6 +59,7 @@ func (s *Subscriber) loop() {
 			instances, err := s.client.GetEntries(s.prefix)
 			if err != nil {
 				s.logger.Log("msg", "failed to retrieve entries", "err", err)
+				s.cache.SetError(err)
 				continue
 			}
 			s.cache.Update(instances)
@@ -65,7 +72,32 @@ func (s *Subscriber) loop() {
 
 // Endpoints implements the Subscriber interface.
 func (s *Subscriber) Endpoints() ([]endpoint.Endpoint, error) {
-	return s.cache.Endpoints(), nil
+	return s.cache.Endpoints()
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (s *Subscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.cache.Instances()
+}
+
+// Metadata implements the MetadataSubscriber interface.
+func (s *Subscriber) Metadata() (map[string]sd.Instance, error) {
+	return s.cache.Metadata(), nil
+}
+
+// Status implements the StatusSubscriber interface.
+func (s *Subscriber) Status() sd.Status {
+	return s.cache.Status()
+}
+
+// Notify implements the NotifyingSubscriber interface.
+func (s *Subscriber) Notify(ch chan<- sd.Event) {
+	s.cache.Notify(ch)
+}
+
+// StopNotify implements the NotifyingSubscriber interface.
+func (s *Subscriber) StopNotify(ch chan<- sd.Event) {
+	s.cache.StopNotify(ch)
 }
 
 // Stop terminates the Subscriber.
@@ -3,6 +3,7 @@ package etcd
 import (
 	"errors"
 	"io"
+	"sync"
 	"testing"
 
 	stdetcd "github.com/coreos/etcd/client"
@@ -71,6 +72,11 @@ func TestBadFactory(t *testing.T) {
 
 type fakeClient struct {
 	responses map[string]*stdetcd.Response
+
+	mtx        sync.Mutex
+	registered map[string]string
+	registers  int
+	err        error
 }
 
 func (c *fakeClient) GetEntries(prefix string) ([]string, error) {
@@ -87,3 +93,42 @@ func (c *fakeClient) GetEntries(prefix string) ([]string, error) {
 }
 
 func (c *fakeClient) WatchPrefix(prefix string, responseChan chan *stdetcd.Response) {}
+
+func (c *fakeClient) Register(s Service) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.registers++
+	if c.err != nil {
+		return c.err
+	}
+	if c.registered == nil {
+		c.registered = map[string]string{}
+	}
+	c.registered[s.Key] = s.Value
+	return nil
+}
+
+func (c *fakeClient) Deregister(s Service) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	delete(c.registered, s.Key)
+	return nil
+}
+
+func (c *fakeClient) Close() {}
+
+func (c *fakeClient) registerCount() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.registers
+}
+
+func (c *fakeClient) value(key string) (string, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	v, ok := c.registered[key]
+	return v, ok
+}
@@ -0,0 +1,319 @@
+package eureka
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+var (
+	// ErrNotFound is returned when Eureka doesn't know the application or
+	// instance. A heartbeat fails with it once the lease of the instance has
+	// expired, and the instance must be registered again.
+	ErrNotFound = errors.New("not found")
+
+	// ErrDeltaDisabled is returned by Client.Delta when the Eureka server is
+	// configured not to serve deltas. The full registry must be fetched
+	// instead.
+	ErrDeltaDisabled = errors.New("delta fetches disabled")
+)
+
+// Instance statuses, as reported by Eureka. Only instances that are UP are
+// yielded by subscribers.
+const (
+	StatusUp           = "UP"
+	StatusDown         = "DOWN"
+	StatusStarting     = "STARTING"
+	StatusOutOfService = "OUT_OF_SERVICE"
+	StatusUnknown      = "UNKNOWN"
+)
+
+// Actions of instances in a delta.
+const (
+	ActionAdded    = "ADDED"
+	ActionModified = "MODIFIED"
+	ActionDeleted  = "DELETED"
+)
+
+// Client is a minimal wrapper around the Eureka REST API, covering the calls
+// needed to follow an application and to register an instance of one.
+type Client interface {
+	// Application returns the registered instances of the named application.
+	Application(ctx context.Context, app string) (Application, error)
+
+	// Delta returns the instances of all applications that changed recently,
+	// each with an ActionType.
+	Delta(ctx context.Context) (Applications, error)
+
+	// Register an instance with Eureka.
+	Register(ctx context.Context, instance Instance) error
+
+	// Heartbeat renews the lease of an instance.
+	Heartbeat(ctx context.Context, app, id string) error
+
+	// Deregister an instance with Eureka.
+	Deregister(ctx context.Context, app, id string) error
+}
+
+// Applications is a set of applications, as returned for deltas.
+type Applications struct {
+	VersionsDelta string        `json:"versions__delta,omitempty"`
+	AppsHashcode  string        `json:"apps__hashcode,omitempty"`
+	Applications  []Application `json:"application"`
+}
+
+// UnmarshalJSON accepts a single application in place of a list, which is how
+// Eureka's JSON serializer renders lists of one.
+func (a *Applications) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		VersionsDelta json.RawMessage `json:"versions__delta"`
+		AppsHashcode  string          `json:"apps__hashcode"`
+		Application   json.RawMessage `json:"application"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	a.VersionsDelta = unquote(raw.VersionsDelta)
+	a.AppsHashcode = raw.AppsHashcode
+	a.Applications = nil
+	if isObject(raw.Application) {
+		a.Applications = make([]Application, 1)
+		return json.Unmarshal(raw.Application, &a.Applications[0])
+	}
+	if len(raw.Application) > 0 {
+		return json.Unmarshal(raw.Application, &a.Applications)
+	}
+	return nil
+}
+
+// Application is a named set of instances.
+type Application struct {
+	Name      string     `json:"name"`
+	Instances []Instance `json:"instance"`
+}
+
+// UnmarshalJSON accepts a single instance in place of a list.
+func (a *Application) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name     string          `json:"name"`
+		Instance json.RawMessage `json:"instance"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	a.Name = raw.Name
+	a.Instances = nil
+	if isObject(raw.Instance) {
+		a.Instances = make([]Instance, 1)
+		return json.Unmarshal(raw.Instance, &a.Instances[0])
+	}
+	if len(raw.Instance) > 0 {
+		return json.Unmarshal(raw.Instance, &a.Instances)
+	}
+	return nil
+}
+
+// Instance mirrors the parts of Eureka's InstanceInfo that are relevant to
+// service discovery.
+type Instance struct {
+	InstanceID     string            `json:"instanceId,omitempty"`
+	HostName       string            `json:"hostName"`
+	App            string            `json:"app"`
+	IPAddr         string            `json:"ipAddr"`
+	VIPAddress     string            `json:"vipAddress,omitempty"`
+	Status         string            `json:"status"`
+	Port           Port              `json:"port"`
+	SecurePort     Port              `json:"securePort"`
+	DataCenterInfo DataCenterInfo    `json:"dataCenterInfo"`
+	LeaseInfo      *LeaseInfo        `json:"leaseInfo,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	ActionType     string            `json:"actionType,omitempty"`
+}
+
+// ID returns the identifier of the instance within its application. Older
+// Eureka servers don't assign instance IDs, and identify instances by their
+// host name instead.
+func (i Instance) ID() string {
+	if i.InstanceID != "" {
+		return i.InstanceID
+	}
+	return i.HostName
+}
+
+// Port is a port of an instance, which is only in use if enabled.
+type Port struct {
+	Port    int
+	Enabled bool
+}
+
+// MarshalJSON renders the port the way Eureka expects it.
+func (p Port) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Port    int    `json:"$"`
+		Enabled string `json:"@enabled"`
+	}{p.Port, strconv.FormatBool(p.Enabled)})
+}
+
+// UnmarshalJSON accepts port numbers and flags rendered as strings, as some
+// Eureka versions do.
+func (p *Port) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Port    json.RawMessage `json:"$"`
+		Enabled json.RawMessage `json:"@enabled"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var err error
+	if s := unquote(raw.Port); s != "" {
+		if p.Port, err = strconv.Atoi(s); err != nil {
+			return fmt.Errorf("invalid port %s", raw.Port)
+		}
+	}
+	if s := unquote(raw.Enabled); s != "" {
+		if p.Enabled, err = strconv.ParseBool(s); err != nil {
+			return fmt.Errorf("invalid port flag %s", raw.Enabled)
+		}
+	}
+	return nil
+}
+
+// DataCenterInfo describes where an instance runs. Eureka requires it on
+// registration.
+type DataCenterInfo struct {
+	Class    string            `json:"@class"`
+	Name     string            `json:"name"` // MyOwn or Amazon
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// LeaseInfo configures the lease of a registered instance.
+type LeaseInfo struct {
+	RenewalIntervalInSecs int `json:"renewalIntervalInSecs,omitempty"`
+	DurationInSecs        int `json:"durationInSecs,omitempty"`
+}
+
+type client struct {
+	url    string
+	client *http.Client
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*client)
+
+// ClientHTTPClient sets the HTTP client used for requests, e.g. to configure
+// timeouts or TLS. By default, http.DefaultClient is used.
+func ClientHTTPClient(hc *http.Client) ClientOption {
+	return func(c *client) { c.client = hc }
+}
+
+// NewClient returns a Client talking to the Eureka server at url, which
+// should be the base URL of the REST API, like "http://10.0.0.1:8761/eureka".
+func NewClient(url string, options ...ClientOption) Client {
+	c := &client{
+		url:    strings.TrimRight(url, "/"),
+		client: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func (c *client) Application(ctx context.Context, app string) (Application, error) {
+	var response struct {
+		Application Application `json:"application"`
+	}
+	err := c.do(ctx, "GET", "/apps/"+app, nil, &response)
+	return response.Application, err
+}
+
+func (c *client) Delta(ctx context.Context) (Applications, error) {
+	var response struct {
+		Applications Applications `json:"applications"`
+	}
+	err := c.do(ctx, "GET", "/apps/delta", nil, &response)
+	if err, ok := err.(statusError); ok && err == http.StatusForbidden {
+		return Applications{}, ErrDeltaDisabled
+	}
+	return response.Applications, err
+}
+
+func (c *client) Register(ctx context.Context, instance Instance) error {
+	return c.do(ctx, "POST", "/apps/"+instance.App, struct {
+		Instance Instance `json:"instance"`
+	}{instance}, nil)
+}
+
+func (c *client) Heartbeat(ctx context.Context, app, id string) error {
+	return c.do(ctx, "PUT", "/apps/"+app+"/"+id, nil, nil)
+}
+
+func (c *client) Deregister(ctx context.Context, app, id string) error {
+	return c.do(ctx, "DELETE", "/apps/"+app+"/"+id, nil, nil)
+}
+
+// do sends a request with the JSON encoding of body, if any, and decodes the
+// response into result, if any.
+func (c *client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var r io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(method, c.url+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := ctxhttp.Do(ctx, c.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	defer io.Copy(ioutil.Discard, resp.Body) // so the connection can be reused
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return statusError(resp.StatusCode)
+	case result == nil:
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// statusError is returned for unexpected response status codes.
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("eureka: %d %s", int(e), http.StatusText(int(e)))
+}
+
+func isObject(data json.RawMessage) bool {
+	return len(data) > 0 && data[0] == '{'
+}
+
+// unquote returns a JSON string or number as a string.
+func unquote(data json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return s
+	}
+	return string(data)
+}
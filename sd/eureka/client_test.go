@@ -0,0 +1,196 @@
+package eureka
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestDecodeApplication(t *testing.T) {
+	for name, data := range map[string]string{
+		"list":   `{"name": "SEARCH", "instance": [{"instanceId": "a", "port": {"$": 8080, "@enabled": true}}]}`,
+		"single": `{"name": "SEARCH", "instance": {"instanceId": "a", "port": {"$": "8080", "@enabled": "true"}}}`,
+	} {
+		var app Application
+		if err := json.Unmarshal([]byte(data), &app); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if want, have := 1, len(app.Instances); want != have {
+			t.Errorf("%s: want %d, have %d", name, want, have)
+			continue
+		}
+		if want, have := (Port{Port: 8080, Enabled: true}), app.Instances[0].Port; want != have {
+			t.Errorf("%s: want %+v, have %+v", name, want, have)
+		}
+	}
+
+	var apps Applications
+	if err := json.Unmarshal([]byte(`{"versions__delta": 3, "apps__hashcode": "UP_1_", "application": {"name": "SEARCH"}}`), &apps); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "3", apps.VersionsDelta; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 1, len(apps.Applications); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestEncodePort(t *testing.T) {
+	data, err := json.Marshal(Port{Port: 8080, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"$":8080,"@enabled":"true"}`, string(data); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	f := newFakeEureka()
+	f.noDelta = true
+	server := httptest.NewServer(f)
+	defer server.Close()
+	c := NewClient(server.URL + "/eureka/")
+
+	if _, err := c.Application(context.Background(), "MISSING"); err != ErrNotFound {
+		t.Errorf("want %v, have %v", ErrNotFound, err)
+	}
+	if err := c.Heartbeat(context.Background(), "MISSING", "a"); err != ErrNotFound {
+		t.Errorf("want %v, have %v", ErrNotFound, err)
+	}
+	if _, err := c.Delta(context.Background()); err != ErrDeltaDisabled {
+		t.Errorf("want %v, have %v", ErrDeltaDisabled, err)
+	}
+}
+
+// fakeEureka implements the parts of the Eureka REST API used by the client.
+// Changes are recorded in a delta, which is cleared as it's fetched.
+type fakeEureka struct {
+	mtx      sync.Mutex
+	apps     map[string]map[string]Instance // by app and ID
+	delta    []Instance
+	noDelta  bool
+	requests map[string]int // by method and path
+}
+
+func newFakeEureka() *fakeEureka {
+	return &fakeEureka{
+		apps:     map[string]map[string]Instance{},
+		requests: map[string]int{},
+	}
+}
+
+func (f *fakeEureka) put(instance Instance) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.putLocked(instance, ActionAdded)
+}
+
+func (f *fakeEureka) putLocked(instance Instance, action string) {
+	app := strings.ToUpper(instance.App)
+	if f.apps[app] == nil {
+		f.apps[app] = map[string]Instance{}
+	}
+	f.apps[app][instance.ID()] = instance
+	instance.ActionType = action
+	f.delta = append(f.delta, instance)
+}
+
+func (f *fakeEureka) remove(app, id string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.removeLocked(app, id)
+}
+
+func (f *fakeEureka) removeLocked(app, id string) bool {
+	instance, ok := f.apps[strings.ToUpper(app)][id]
+	if !ok {
+		return false
+	}
+	delete(f.apps[strings.ToUpper(app)], id)
+	instance.ActionType = ActionDeleted
+	f.delta = append(f.delta, instance)
+	return true
+}
+
+func (f *fakeEureka) get(app, id string) (Instance, bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	instance, ok := f.apps[strings.ToUpper(app)][id]
+	return instance, ok
+}
+
+func (f *fakeEureka) count(method, path string) int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.requests[method+" "+path]
+}
+
+func (f *fakeEureka) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.requests[r.Method+" "+r.URL.Path]++
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/eureka/apps/"), "/")
+	app := strings.ToUpper(parts[0])
+	switch {
+	case r.Method == "GET" && parts[0] == "delta":
+		if f.noDelta {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		byApp := map[string][]Instance{}
+		for _, instance := range f.delta {
+			byApp[instance.App] = append(byApp[instance.App], instance)
+		}
+		f.delta = nil
+		var apps Applications
+		for name, instances := range byApp {
+			apps.Applications = append(apps.Applications, Application{Name: name, Instances: instances})
+		}
+		json.NewEncoder(w).Encode(map[string]Applications{"applications": apps})
+
+	case r.Method == "GET" && len(parts) == 1:
+		instances, ok := f.apps[app]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		a := Application{Name: app}
+		for _, instance := range instances {
+			a.Instances = append(a.Instances, instance)
+		}
+		json.NewEncoder(w).Encode(map[string]Application{"application": a})
+
+	case r.Method == "POST" && len(parts) == 1:
+		var request struct {
+			Instance Instance `json:"instance"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.putLocked(request.Instance, ActionAdded)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == "PUT" && len(parts) == 2:
+		if _, ok := f.apps[app][parts[1]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+	case r.Method == "DELETE" && len(parts) == 2:
+		if !f.removeLocked(app, parts[1]) {
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
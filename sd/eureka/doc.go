@@ -0,0 +1,5 @@
+// Package eureka provides subscriber and registrar implementations for
+// Netflix Eureka. Both talk to the Eureka REST API with plain net/http, so
+// they need no client library, and interoperate with JVM services using the
+// Netflix clients.
+package eureka
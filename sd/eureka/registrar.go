@@ -0,0 +1,138 @@
+package eureka
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log"
+)
+
+// DefaultRenewalInterval is the default interval at which a Registrar renews
+// the lease of its instance, the same as the Netflix client's.
+const DefaultRenewalInterval = 30 * time.Second
+
+// Registrar registers service instance liveness information to Eureka.
+// Eureka expires instances whose leases aren't renewed, so the Registrar
+// sends heartbeats until Deregister is called.
+type Registrar struct {
+	client   Client
+	instance Instance
+	logger   log.Logger
+	interval time.Duration
+	timeout  time.Duration // of each request
+	quitmtx  sync.Mutex
+	quit     chan struct{}
+	cancel   context.CancelFunc // of the heartbeat in flight
+	done     chan struct{}
+}
+
+// NewRegistrar returns a Eureka Registrar acting on the provided instance.
+// Unless set, the instance's status defaults to UP, and its data center to
+// MyOwn, which Eureka requires. Heartbeats are sent at the lease renewal
+// interval of the instance, or DefaultRenewalInterval if it has none, and
+// each request to Eureka times out after the same interval.
+func NewRegistrar(client Client, instance Instance, logger log.Logger) *Registrar {
+	if instance.Status == "" {
+		instance.Status = StatusUp
+	}
+	if instance.DataCenterInfo.Name == "" {
+		instance.DataCenterInfo = DataCenterInfo{
+			Class: "com.netflix.appinfo.InstanceInfo$DefaultDataCenterInfo",
+			Name:  "MyOwn",
+		}
+	}
+	interval := DefaultRenewalInterval
+	if instance.LeaseInfo != nil && instance.LeaseInfo.RenewalIntervalInSecs > 0 {
+		interval = time.Duration(instance.LeaseInfo.RenewalIntervalInSecs) * time.Second
+	}
+	return &Registrar{
+		client:   client,
+		instance: instance,
+		logger: log.NewContext(logger).With(
+			"app", instance.App,
+			"id", instance.ID(),
+		),
+		interval: interval,
+		timeout:  interval,
+	}
+}
+
+// Register implements sd.Registrar interface. The lease of the instance is
+// renewed in the background until Deregister is called.
+func (r *Registrar) Register() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	err := r.client.Register(ctx, r.instance)
+	cancel()
+	if err != nil {
+		r.logger.Log("err", err)
+	} else {
+		r.logger.Log("action", "register")
+	}
+
+	r.quitmtx.Lock()
+	defer r.quitmtx.Unlock()
+	if r.quit != nil {
+		return // already heartbeating
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	r.quit = make(chan struct{})
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.loop(ctx, r.quit, r.done)
+}
+
+func (r *Registrar) loop(ctx context.Context, quit, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.heartbeat(ctx)
+		case <-quit:
+			return
+		}
+	}
+}
+
+// heartbeat renews the lease of the instance. If Eureka no longer knows the
+// instance, because the lease expired or the registration failed, it's
+// registered again. Other failures are retried on the next heartbeat.
+func (r *Registrar) heartbeat(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	err := r.client.Heartbeat(ctx, r.instance.App, r.instance.ID())
+	if err == ErrNotFound {
+		if err = r.client.Register(ctx, r.instance); err == nil {
+			r.logger.Log("action", "reregister")
+		}
+	}
+	if err != nil {
+		r.logger.Log("err", err, "action", "heartbeat")
+	}
+}
+
+// Deregister implements sd.Registrar interface.
+func (r *Registrar) Deregister() {
+	// Stop the heartbeat and wait for it, so that a heartbeat in flight can't
+	// register the instance again after it's been deregistered. The
+	// heartbeat is cancelled, so that a hung request doesn't hold us up.
+	r.quitmtx.Lock()
+	if r.quit != nil {
+		r.cancel()
+		close(r.quit)
+		<-r.done
+		r.quit, r.cancel, r.done = nil, nil, nil
+	}
+	r.quitmtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if err := r.client.Deregister(ctx, r.instance.App, r.instance.ID()); err != nil {
+		r.logger.Log("err", err)
+	} else {
+		r.logger.Log("action", "deregister")
+	}
+}
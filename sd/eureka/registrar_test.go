@@ -0,0 +1,106 @@
+package eureka
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRegistrar(t *testing.T) {
+	f := newFakeEureka()
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	r := NewRegistrar(NewClient(server.URL+"/eureka"), Instance{
+		HostName: "a.local",
+		App:      "SEARCH",
+		IPAddr:   "10.0.0.1",
+		Port:     Port{Port: 8080, Enabled: true},
+	}, log.NewNopLogger())
+	r.interval = time.Millisecond
+
+	r.Register()
+	registered, ok := f.get("SEARCH", "a.local")
+	if !ok {
+		t.Fatal("instance not registered")
+	}
+	if want, have := StatusUp, registered.Status; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "MyOwn", registered.DataCenterInfo.Name; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	waitFor(t, func() bool { return f.count("PUT", "/eureka/apps/SEARCH/a.local") >= 2 })
+
+	// The lease expires, and the instance is registered again on the next
+	// heartbeat.
+	f.remove("SEARCH", "a.local")
+	waitFor(t, func() bool { return f.count("POST", "/eureka/apps/SEARCH") >= 2 })
+	if _, ok := f.get("SEARCH", "a.local"); !ok {
+		t.Error("instance not registered again")
+	}
+
+	r.Deregister()
+	if _, ok := f.get("SEARCH", "a.local"); ok {
+		t.Error("instance still registered")
+	}
+	heartbeats := f.count("PUT", "/eureka/apps/SEARCH/a.local")
+	time.Sleep(20 * time.Millisecond)
+	if want, have := heartbeats, f.count("PUT", "/eureka/apps/SEARCH/a.local"); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestRegistrarDeregisterDuringHeartbeat(t *testing.T) {
+	c := &hangingClient{heartbeats: make(chan struct{}, 1)}
+	r := NewRegistrar(c, Instance{HostName: "a.local", App: "SEARCH"}, log.NewNopLogger())
+	r.interval = time.Millisecond
+
+	r.Register()
+	<-c.heartbeats
+
+	// The heartbeat in flight never returns by itself.
+	done := make(chan struct{})
+	go func() {
+		r.Deregister()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Deregister blocked by heartbeat")
+	}
+}
+
+// hangingClient blocks heartbeats until they're cancelled.
+type hangingClient struct {
+	Client
+	heartbeats chan struct{}
+}
+
+func (c *hangingClient) Register(context.Context, Instance) error         { return nil }
+func (c *hangingClient) Deregister(context.Context, string, string) error { return nil }
+
+func (c *hangingClient) Heartbeat(ctx context.Context, app, id string) error {
+	select {
+	case c.heartbeats <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,289 @@
+package eureka
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/cache"
+)
+
+const (
+	// DefaultRefreshInterval is the default interval at which a Subscriber
+	// polls Eureka, the same as the Netflix client's.
+	DefaultRefreshInterval = 30 * time.Second
+
+	// DefaultFullFetchInterval is the default interval at which a Subscriber
+	// fetches the application in full, rather than a delta.
+	DefaultFullFetchInterval = 5 * time.Minute
+
+	// deltaWindow is how long after a fetch a delta is trusted to hold every
+	// change since. Eureka keeps changes for 3 minutes by default; the rest
+	// is a margin for slow requests.
+	deltaWindow = 2 * time.Minute
+)
+
+// Subscriber yields endpoints for the instances of an application in Eureka
+// that are UP. Eureka has no watches, so it's polled. After the initial fetch
+// of the application, the Subscriber fetches deltas, which are much smaller
+// than the application on large registries. Eureka only keeps deltas for a
+// few minutes, so the application is fetched in full again after any
+// failure, if the previous fetch is too old for a delta to cover, and
+// periodically in any case, in case a change has been missed. It's always
+// fetched in full if the server doesn't serve deltas.
+type Subscriber struct {
+	cache           *cache.Cache
+	client          Client
+	logger          log.Logger
+	app             string
+	refreshInterval time.Duration
+	fullInterval    time.Duration
+	cacheOptions    []cache.Option
+	instances       map[string]Instance // by ID, regardless of status
+	last            []sd.Instance       // as of the last update of the cache
+	updated         bool
+	ctx             context.Context
+	cancel          context.CancelFunc
+}
+
+var (
+	_ sd.MetadataSubscriber  = &Subscriber{}
+	_ sd.StatusSubscriber    = &Subscriber{}
+	_ sd.NotifyingSubscriber = &Subscriber{}
+)
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberRefreshInterval sets the interval at which the Subscriber polls
+// Eureka. By default, DefaultRefreshInterval.
+func SubscriberRefreshInterval(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.refreshInterval = d }
+}
+
+// SubscriberFullFetchInterval sets the interval at which the Subscriber
+// fetches the application in full, rather than a delta. By default,
+// DefaultFullFetchInterval.
+func SubscriberFullFetchInterval(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.fullInterval = d }
+}
+
+// SubscriberCacheOptions configures the endpoint cache of the Subscriber.
+func SubscriberCacheOptions(options ...cache.Option) SubscriberOption {
+	return func(s *Subscriber) { s.cacheOptions = append(s.cacheOptions, options...) }
+}
+
+// NewSubscriber returns a Eureka subscriber which returns endpoints for the
+// instances of the named application. An instance's endpoint is at its IP
+// address, or host name if it has none, and its port, or secure port if only
+// that is enabled.
+func NewSubscriber(client Client, factory sd.Factory, logger log.Logger, app string, options ...SubscriberOption) *Subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Subscriber{
+		client:          client,
+		logger:          log.NewContext(logger).With("app", app),
+		app:             app,
+		refreshInterval: DefaultRefreshInterval,
+		fullInterval:    DefaultFullFetchInterval,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.cache = cache.New(factory, s.logger, s.cacheOptions...)
+
+	var synced time.Time
+	start := time.Now()
+	if err := s.fetch(); err != nil {
+		s.logger.Log("err", err)
+		s.cache.SetError(err)
+	} else {
+		synced = start
+	}
+
+	go s.loop(synced)
+	return s
+}
+
+// Endpoints implements the Subscriber interface.
+func (s *Subscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	return s.cache.Endpoints()
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (s *Subscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.cache.Instances()
+}
+
+// Metadata implements the MetadataSubscriber interface.
+func (s *Subscriber) Metadata() (map[string]sd.Instance, error) {
+	return s.cache.Metadata(), nil
+}
+
+// Status implements the StatusSubscriber interface.
+func (s *Subscriber) Status() sd.Status {
+	return s.cache.Status()
+}
+
+// Notify implements the NotifyingSubscriber interface.
+func (s *Subscriber) Notify(ch chan<- sd.Event) {
+	s.cache.Notify(ch)
+}
+
+// StopNotify implements the NotifyingSubscriber interface.
+func (s *Subscriber) StopNotify(ch chan<- sd.Event) {
+	s.cache.StopNotify(ch)
+}
+
+// Stop terminates the subscriber.
+func (s *Subscriber) Stop() {
+	s.cancel()
+}
+
+// loop polls Eureka. The synced time is the start of the last successful fetch, i.e.
+// the time after which changes must be fetched, or zero if there was none.
+func (s *Subscriber) loop(synced time.Time) {
+	var (
+		delta    = true
+		lastFull = synced
+		ticker   = time.NewTicker(s.refreshInterval)
+	)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+
+		var (
+			start = time.Now()
+			full  = !delta || synced.IsZero() || start.Sub(synced) >= deltaWindow || start.Sub(lastFull) >= s.fullInterval
+			err   error
+		)
+		if !full {
+			err = s.fetchDelta()
+			if err == ErrDeltaDisabled {
+				s.logger.Log("msg", "delta fetches disabled by server, fetching application in full")
+				delta, full = false, true
+			}
+		}
+		if full {
+			if err = s.fetch(); err == nil {
+				lastFull = start
+			}
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		default:
+		}
+		if err != nil {
+			s.logger.Log("err", err)
+			s.cache.SetError(err)
+			synced = time.Time{}
+		} else {
+			synced = start
+		}
+	}
+}
+
+// fetch replaces the instances with the application's. If it fails, the
+// cache isn't updated, so potentially-good endpoints aren't replaced with
+// nothing.
+func (s *Subscriber) fetch() error {
+	app, err := s.client.Application(s.ctx, s.app)
+	if err == ErrNotFound {
+		app, err = Application{}, nil // no instances registered
+	}
+	if err != nil {
+		return err
+	}
+	s.instances = make(map[string]Instance, len(app.Instances))
+	for _, instance := range app.Instances {
+		s.instances[instance.ID()] = instance
+	}
+	s.update()
+	return nil
+}
+
+// fetchDelta applies the recent changes to the application's instances.
+func (s *Subscriber) fetchDelta() error {
+	apps, err := s.client.Delta(s.ctx)
+	if err != nil {
+		return err
+	}
+	for _, app := range apps.Applications {
+		if !strings.EqualFold(app.Name, s.app) {
+			continue
+		}
+		for _, instance := range app.Instances {
+			switch instance.ActionType {
+			case ActionDeleted:
+				delete(s.instances, instance.ID())
+			default:
+				s.instances[instance.ID()] = instance
+			}
+		}
+	}
+	s.update()
+	return nil
+}
+
+// update passes the instances on to the cache. Polls that change nothing,
+// like empty deltas, are skipped, so that consumers of the cache don't start
+// over, unless the cache has to be told that Eureka is reachable again.
+func (s *Subscriber) update() {
+	instances := makeInstances(s.instances)
+	if s.updated && reflect.DeepEqual(instances, s.last) && s.cache.Status().Err == nil {
+		return
+	}
+	s.logger.Log("instances", len(instances))
+	s.cache.UpdateInstances(instances)
+	s.last, s.updated = instances, true
+}
+
+func makeInstances(instances map[string]Instance) []sd.Instance {
+	ids := make([]string, 0, len(instances))
+	for id := range instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var result []sd.Instance
+	for _, id := range ids {
+		instance := instances[id]
+		if instance.Status != StatusUp {
+			continue
+		}
+		host := instance.IPAddr
+		if host == "" {
+			host = instance.HostName
+		}
+		port := instance.Port.Port
+		if !instance.Port.Enabled && instance.SecurePort.Enabled {
+			port = instance.SecurePort.Port
+		}
+		zone := instance.Metadata["zone"]
+		if zone == "" {
+			zone = instance.DataCenterInfo.Metadata["availability-zone"]
+		}
+		weight, _ := strconv.Atoi(instance.Metadata["weight"])
+		result = append(result, sd.Instance{
+			Addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+			Weight: weight,
+			Zone:   zone,
+		})
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package eureka
+
+import (
+	"io"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+func TestSubscriber(t *testing.T) {
+	f := newFakeEureka()
+	f.put(instance("search", "a", "10.0.0.1", StatusUp))
+	f.put(instance("search", "b", "10.0.0.2", StatusStarting))
+	f.put(instance("other", "c", "10.0.0.3", StatusUp))
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL+"/eureka"), factory, log.NewNopLogger(), "search", SubscriberRefreshInterval(time.Millisecond))
+	defer s.Stop()
+	assertInstances(t, s, "10.0.0.1:8080")
+
+	b := instance("search", "b", "10.0.0.2", StatusUp)
+	b.Port.Enabled = false
+	b.SecurePort = Port{Port: 8443, Enabled: true}
+	f.put(b)
+	f.put(instance("other", "d", "10.0.0.4", StatusUp))
+	assertInstances(t, s, "10.0.0.1:8080", "10.0.0.2:8443")
+
+	f.remove("search", "a")
+	assertInstances(t, s, "10.0.0.2:8443")
+
+	// Changes were picked up from deltas, rather than by fetching the
+	// application again.
+	if want, have := 1, f.count("GET", "/eureka/apps/search"); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestSubscriberSkipsUnchanged(t *testing.T) {
+	f := newFakeEureka()
+	f.put(instance("search", "a", "10.0.0.1", StatusUp))
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL+"/eureka"), factory, log.NewNopLogger(), "search", SubscriberRefreshInterval(time.Millisecond))
+	defer s.Stop()
+	lastUpdate := s.Status().LastUpdate
+
+	// Empty deltas, and changes to other applications, don't touch the cache.
+	f.put(instance("other", "b", "10.0.0.2", StatusUp))
+	polls := f.count("GET", "/eureka/apps/delta")
+	waitFor(t, func() bool { return f.count("GET", "/eureka/apps/delta") >= polls+5 })
+	if want, have := lastUpdate, s.Status().LastUpdate; !want.Equal(have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestSubscriberDeltaDisabled(t *testing.T) {
+	f := newFakeEureka()
+	f.noDelta = true
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL+"/eureka"), factory, log.NewNopLogger(), "search", SubscriberRefreshInterval(time.Millisecond))
+	defer s.Stop()
+	assertInstances(t, s)
+
+	f.put(instance("search", "a", "10.0.0.1", StatusUp))
+	assertInstances(t, s, "10.0.0.1:8080")
+
+	// Deltas aren't asked for again once the server refused them.
+	if want, have := 1, f.count("GET", "/eureka/apps/delta"); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if _, err := s.Endpoints(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscriberFullFetch(t *testing.T) {
+	f := newFakeEureka()
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL+"/eureka"), factory, log.NewNopLogger(), "search",
+		SubscriberRefreshInterval(time.Millisecond),
+		SubscriberFullFetchInterval(20*time.Millisecond),
+	)
+	defer s.Stop()
+	assertInstances(t, s)
+
+	// The change drops out of the delta before it's fetched, and is only
+	// picked up by fetching the application again.
+	f.put(instance("search", "a", "10.0.0.1", StatusUp))
+	f.mtx.Lock()
+	f.delta = nil
+	f.mtx.Unlock()
+	assertInstances(t, s, "10.0.0.1:8080")
+
+	if f.count("GET", "/eureka/apps/delta") == 0 {
+		t.Error("want deltas fetched in between, have none")
+	}
+}
+
+func TestSubscriberMetadata(t *testing.T) {
+	f := newFakeEureka()
+	a := instance("search", "a", "10.0.0.1", StatusUp)
+	a.Metadata = map[string]string{"weight": "3"}
+	a.DataCenterInfo.Metadata = map[string]string{"availability-zone": "us-east-1a"}
+	f.put(a)
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL+"/eureka"), factory, log.NewNopLogger(), "search")
+	defer s.Stop()
+
+	metadata, err := s.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := metadata["10.0.0.1:8080"]; have.Weight != 3 || have.Zone != "us-east-1a" {
+		t.Errorf("want weight 3 in zone us-east-1a, have %+v", have)
+	}
+}
+
+func instance(app, id, ip, status string) Instance {
+	return Instance{
+		InstanceID: id,
+		HostName:   id + ".local",
+		App:        strings.ToUpper(app),
+		IPAddr:     ip,
+		Status:     status,
+		Port:       Port{Port: 8080, Enabled: true},
+	}
+}
+
+func factory(instance string) (endpoint.Endpoint, io.Closer, error) {
+	return endpoint.Nop, nil, nil
+}
+
+func assertInstances(t *testing.T, s *Subscriber, want ...string) {
+	var have []string
+	deadline := time.Now().Add(time.Second)
+	for {
+		instances, _ := s.Instances()
+		have = have[:0]
+		for instance := range instances {
+			have = append(have, instance)
+		}
+		sort.Strings(have)
+		if strings.Join(want, ",") == strings.Join(have, ",") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %v, have %v", want, have)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
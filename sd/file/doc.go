@@ -0,0 +1,4 @@
+// Package file provides a subscriber that reads instances from a local file,
+// and follows changes to it. It's meant for environments without a service
+// discovery system, where the file is managed by hand or by config management.
+package file
@@ -0,0 +1,220 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/cache"
+)
+
+// Subscriber yields endpoints for the instances listed in a file. The file is
+// read on a fixed schedule, and reloaded whenever its contents change. That
+// works the same on every platform and filesystem, and it's cheap for the
+// small files this is meant for.
+//
+// The format is chosen by extension. Files ending in .json hold a JSON array,
+// and files ending in .yaml or .yml a YAML sequence. Their elements are either
+// instance strings, or objects with an addr and optional weight, zone and
+// tags, which are passed on as instance metadata. Any other file has one
+// instance string per line; blank lines and lines starting with # are
+// ignored.
+type Subscriber struct {
+	path   string
+	cache  *cache.Cache
+	logger log.Logger
+	data   []byte
+	quit   chan struct{}
+}
+
+var (
+	_ sd.MetadataSubscriber  = &Subscriber{}
+	_ sd.StatusSubscriber    = &Subscriber{}
+	_ sd.NotifyingSubscriber = &Subscriber{}
+)
+
+// NewSubscriber returns a file subscriber, which checks the file at path for
+// changes every interval. The options configure the endpoint cache.
+func NewSubscriber(path string, interval time.Duration, factory sd.Factory, logger log.Logger, options ...cache.Option) *Subscriber {
+	s := &Subscriber{
+		path:   path,
+		cache:  cache.New(factory, logger, options...),
+		logger: log.NewContext(logger).With("path", path),
+		quit:   make(chan struct{}),
+	}
+
+	instances, _, err := s.load()
+	if err == nil {
+		s.logger.Log("instances", len(instances))
+		s.cache.UpdateInstances(instances)
+	} else {
+		s.logger.Log("err", err)
+		s.cache.SetError(err)
+	}
+
+	go s.loop(time.NewTicker(interval))
+	return s
+}
+
+// Endpoints implements the Subscriber interface.
+func (s *Subscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	return s.cache.Endpoints()
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (s *Subscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.cache.Instances()
+}
+
+// Metadata implements the MetadataSubscriber interface.
+func (s *Subscriber) Metadata() (map[string]sd.Instance, error) {
+	return s.cache.Metadata(), nil
+}
+
+// Status implements the StatusSubscriber interface.
+func (s *Subscriber) Status() sd.Status {
+	return s.cache.Status()
+}
+
+// Notify implements the NotifyingSubscriber interface.
+func (s *Subscriber) Notify(ch chan<- sd.Event) {
+	s.cache.Notify(ch)
+}
+
+// StopNotify implements the NotifyingSubscriber interface.
+func (s *Subscriber) StopNotify(ch chan<- sd.Event) {
+	s.cache.StopNotify(ch)
+}
+
+// Stop terminates the Subscriber.
+func (s *Subscriber) Stop() {
+	close(s.quit)
+}
+
+func (s *Subscriber) loop(t *time.Ticker) {
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			instances, changed, err := s.load()
+			if err != nil {
+				s.logger.Log("err", err)
+				s.cache.SetError(err)
+				continue // don't replace potentially-good with bad
+			}
+			if changed {
+				s.logger.Log("instances", len(instances))
+				s.cache.UpdateInstances(instances)
+			}
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// load reads the file, and parses it if it changed since the last successful
+// load. Modification times aren't used, as their granularity is too coarse to
+// catch quick successive writes. The recorded contents are forgotten on
+// failure, so that the next successful load updates the cache, and thereby
+// clears the error, even if the file ends up as it was.
+func (s *Subscriber) load() ([]sd.Instance, bool, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		s.data = nil
+		return nil, false, err
+	}
+	if s.data != nil && bytes.Equal(data, s.data) {
+		return nil, false, nil
+	}
+
+	instances, err := parse(s.path, data)
+	if err != nil {
+		s.data = nil
+		return nil, false, err
+	}
+
+	s.data = data
+	return instances, true, nil
+}
+
+func parse(path string, data []byte) ([]sd.Instance, error) {
+	var instances []sd.Instance
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var entries []entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+		instances = makeInstances(entries)
+	case ".yaml", ".yml":
+		var entries []entry
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+		instances = makeInstances(entries)
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			instances = append(instances, sd.Instance{Addr: line})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return instances, nil
+}
+
+// entry is an element of a JSON or YAML file: either a plain instance string,
+// or an object describing the instance.
+type entry struct {
+	Addr   string   `json:"addr" yaml:"addr"`
+	Weight int      `json:"weight" yaml:"weight"`
+	Zone   string   `json:"zone" yaml:"zone"`
+	Tags   []string `json:"tags" yaml:"tags"`
+}
+
+func (e *entry) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.Addr); err == nil {
+		return nil
+	}
+	type plain entry // without the UnmarshalJSON method
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+func (e *entry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&e.Addr); err == nil {
+		return nil
+	}
+	type plain entry // without the UnmarshalYAML method
+	return unmarshal((*plain)(e))
+}
+
+func makeInstances(entries []entry) []sd.Instance {
+	instances := make([]sd.Instance, 0, len(entries))
+	for _, e := range entries {
+		if e.Addr == "" {
+			continue
+		}
+		instances = append(instances, sd.Instance{
+			Addr:   e.Addr,
+			Weight: e.Weight,
+			Zone:   e.Zone,
+			Tags:   e.Tags,
+		})
+	}
+	return instances
+}
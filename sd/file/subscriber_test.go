@@ -0,0 +1,157 @@
+package file
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		data string
+		want []string
+	}{
+		{"instances.json", `["10.0.0.1:80", "10.0.0.2:80"]`, []string{"10.0.0.1:80", "10.0.0.2:80"}},
+		{"instances.JSON", `[]`, nil},
+		{"instances.yaml", "- 10.0.0.1:80\n- 10.0.0.2:80\n", []string{"10.0.0.1:80", "10.0.0.2:80"}},
+		{"instances.yml", "# staging\n- 10.0.0.1:80\n", []string{"10.0.0.1:80"}},
+		{"instances", "10.0.0.1:80\n\n  # down for maintenance\n# 10.0.0.2:80\n  10.0.0.3:80  \n", []string{"10.0.0.1:80", "10.0.0.3:80"}},
+		{"instances.txt", "10.0.0.1:80", []string{"10.0.0.1:80"}},
+	} {
+		instances, err := parse(tc.path, []byte(tc.data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.path, err)
+			continue
+		}
+		var have []string
+		for _, instance := range instances {
+			have = append(have, instance.Addr)
+		}
+		if strings.Join(tc.want, ",") != strings.Join(have, ",") {
+			t.Errorf("%s: want %v, have %v", tc.path, tc.want, have)
+		}
+	}
+
+	for _, path := range []string{"bad.json", "bad.yaml"} {
+		if _, err := parse(path, []byte("{not: [a list")); err == nil {
+			t.Errorf("%s: want error, have none", path)
+		}
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	for path, data := range map[string]string{
+		"instances.json": `["10.0.0.1:80", {"addr": "10.0.0.2:80", "weight": 3, "zone": "b", "tags": ["canary"]}]`,
+		"instances.yaml": "- 10.0.0.1:80\n- addr: 10.0.0.2:80\n  weight: 3\n  zone: b\n  tags: [canary]\n",
+	} {
+		instances, err := parse(path, []byte(data))
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if want, have := 2, len(instances); want != have {
+			t.Errorf("%s: want %d, have %d", path, want, have)
+			continue
+		}
+		if want, have := (sd.Instance{Addr: "10.0.0.1:80"}), instances[0]; want.Addr != have.Addr || have.Weight != 0 || have.Zone != "" || len(have.Tags) != 0 {
+			t.Errorf("%s: want %+v, have %+v", path, want, have)
+		}
+		if have := instances[1]; have.Addr != "10.0.0.2:80" || have.Weight != 3 || have.Zone != "b" || !have.HasTag("canary") {
+			t.Errorf("%s: want 10.0.0.2:80 with weight 3, zone b and tag canary, have %+v", path, have)
+		}
+	}
+}
+
+func TestSubscriber(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sd-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "instances.json")
+
+	write(t, path, `["10.0.0.1:80", "10.0.0.2:80"]`)
+	s := NewSubscriber(path, time.Millisecond, factory, log.NewNopLogger())
+	defer s.Stop()
+	assertInstances(t, s, "10.0.0.1:80", "10.0.0.2:80")
+
+	write(t, path, `["10.0.0.2:80", "10.0.0.3:80"]`)
+	assertInstances(t, s, "10.0.0.2:80", "10.0.0.3:80")
+
+	// A broken file doesn't replace the last good set of instances.
+	write(t, path, `["10.0.0.4:80",`)
+	time.Sleep(20 * time.Millisecond)
+	assertInstances(t, s, "10.0.0.2:80", "10.0.0.3:80")
+
+	write(t, path, `["10.0.0.4:80"]`)
+	assertInstances(t, s, "10.0.0.4:80")
+
+	// Neither does a missing one.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	assertInstances(t, s, "10.0.0.4:80")
+}
+
+func TestSubscriberMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sd-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "instances")
+
+	s := NewSubscriber(path, time.Millisecond, factory, log.NewNopLogger())
+	defer s.Stop()
+	assertInstances(t, s)
+
+	write(t, path, "10.0.0.1:80\n")
+	assertInstances(t, s, "10.0.0.1:80")
+}
+
+func factory(instance string) (endpoint.Endpoint, io.Closer, error) {
+	return endpoint.Nop, nil, nil
+}
+
+// write replaces the file atomically, like most config management tools do,
+// so the subscriber never sees it half-written.
+func write(t *testing.T, path, data string) {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertInstances(t *testing.T, s *Subscriber, want ...string) {
+	var have []string
+	deadline := time.Now().Add(time.Second)
+	for {
+		instances, _ := s.Instances()
+		have = have[:0]
+		for instance := range instances {
+			have = append(have, instance)
+		}
+		sort.Strings(have)
+		if strings.Join(want, ",") == strings.Join(have, ",") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %v, have %v", want, have)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
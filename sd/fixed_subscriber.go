@@ -1,9 +1,63 @@
 package sd
 
-import "github.com/go-kit/kit/endpoint"
+import (
+	"sort"
+
+	"github.com/go-kit/kit/endpoint"
+)
 
 // FixedSubscriber yields a fixed set of services.
 type FixedSubscriber []endpoint.Endpoint
 
 // Endpoints implements Subscriber.
 func (s FixedSubscriber) Endpoints() ([]endpoint.Endpoint, error) { return s, nil }
+
+// FixedInstanceSubscriber yields a fixed set of services, keyed by instance
+// string.
+type FixedInstanceSubscriber struct {
+	instances map[string]endpoint.Endpoint
+	endpoints []endpoint.Endpoint // ordered by instance string
+}
+
+// FixedInstances returns a FixedInstanceSubscriber yielding the endpoints,
+// keyed by instance string. The map must not be modified afterwards.
+func FixedInstances(instances map[string]endpoint.Endpoint) *FixedInstanceSubscriber {
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	endpoints := make([]endpoint.Endpoint, len(names))
+	for i, name := range names {
+		endpoints[i] = instances[name]
+	}
+	return &FixedInstanceSubscriber{instances: instances, endpoints: endpoints}
+}
+
+// NewFixedInstanceSubscriber returns a FixedInstanceSubscriber of the
+// endpoints the factory makes for the instances. Unlike a FixedSubscriber,
+// its endpoints are keyed by instance string, like those from a service
+// discovery system, so that a Union deduplicates the two. The endpoints are
+// never closed.
+func NewFixedInstanceSubscriber(factory Factory, instances ...string) (*FixedInstanceSubscriber, error) {
+	m := make(map[string]endpoint.Endpoint, len(instances))
+	for _, instance := range instances {
+		e, _, err := factory(instance)
+		if err != nil {
+			return nil, err
+		}
+		m[instance] = e
+	}
+	return FixedInstances(m), nil
+}
+
+// Endpoints implements Subscriber. Endpoints are ordered lexicographically by
+// the corresponding instance string, and the same slice is always returned.
+func (s *FixedInstanceSubscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	return s.endpoints, nil
+}
+
+// Instances implements InstanceSubscriber.
+func (s *FixedInstanceSubscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.instances, nil
+}
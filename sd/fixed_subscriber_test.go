@@ -0,0 +1,28 @@
+package sd
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestFixedInstanceSubscriber(t *testing.T) {
+	s := FixedInstances(map[string]endpoint.Endpoint{"b": named("b"), "a": named("a")})
+
+	first, err := s.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response, _ := first[0](context.Background(), struct{}{}); response != "a" {
+		t.Errorf("want a first, have %v", response)
+	}
+
+	// Consumers that rebuild their state whenever they're given a different
+	// slice must be given the same one every time.
+	second, _ := s.Endpoints()
+	if &first[0] != &second[0] {
+		t.Error("want the same slice, have a new one")
+	}
+}
@@ -0,0 +1,66 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Check probes a single instance, and returns an error if it's unhealthy. It's
+// passed the instance string, and the endpoint made from it by the factory.
+// The context is canceled when the check times out.
+type Check func(ctx context.Context, instance string, e endpoint.Endpoint) error
+
+// DialCheck returns a Check that succeeds if a connection to the instance can
+// be established on the given network, e.g. "tcp". The instance string must
+// be an address, like host:port.
+func DialCheck(network string) Check {
+	return func(ctx context.Context, instance string, _ endpoint.Endpoint) error {
+		timeout := time.Duration(0)
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = deadline.Sub(time.Now())
+		}
+		conn, err := net.DialTimeout(network, instance, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTPCheck returns a Check that makes a GET request to the given path on the
+// instance, e.g. "/health", and succeeds if the response status is 2xx. The
+// instance string must be a host:port. If client is nil,
+// http.DefaultClient is used.
+func HTTPCheck(client *http.Client, path string) Check {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context, instance string, _ endpoint.Endpoint) error {
+		resp, err := ctxhttp.Get(ctx, client, "http://"+instance+path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("health check responded %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// EndpointCheck returns a Check that invokes the endpoint of the instance
+// with the given request, and succeeds if it returns no error. It's meant for
+// services with a dedicated health method.
+func EndpointCheck(request interface{}) Check {
+	return func(ctx context.Context, _ string, e endpoint.Endpoint) error {
+		_, err := e(ctx, request)
+		return err
+	}
+}
@@ -0,0 +1,4 @@
+// Package healthcheck provides a subscriber that actively checks the health of
+// the instances yielded by another subscriber, and only yields the endpoints of
+// those that are healthy.
+package healthcheck
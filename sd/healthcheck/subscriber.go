@@ -0,0 +1,296 @@
+package healthcheck
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/identity"
+)
+
+// Subscriber wraps another subscriber, and periodically checks the health of
+// each of its instances. Instances that fail a number of consecutive checks
+// are considered unhealthy, and their endpoints are withheld until they pass
+// a number of consecutive checks again. New instances are considered healthy
+// until proven otherwise.
+//
+// The endpoints of subscribers that aren't InstanceSubscribers, like a
+// sd.FixedSubscriber, are keyed by their position, as "#j" for the jth
+// endpoint, counting from zero. Such keys only suit checks that don't need an
+// address, like EndpointCheck.
+type Subscriber struct {
+	s        sd.Subscriber
+	check    Check
+	logger   log.Logger
+	interval time.Duration
+	timeout  time.Duration
+	fall     int
+	rise     int
+	quitc    chan struct{}
+	stopOnce sync.Once
+
+	mtx     sync.Mutex
+	states  map[string]*state
+	version uint64 // incremented whenever an instance changes health
+
+	// The endpoints of s keyed by position, if it isn't an InstanceSubscriber,
+	// and the slice they were keyed from.
+	keyedSource []endpoint.Endpoint
+	keyed       map[string]endpoint.Endpoint
+
+	// The most recent result of Endpoints and Instances, reused as long as
+	// neither the instances nor their health change, so that balancers see
+	// a stable set of endpoints. The instances of the wrapped subscriber they
+	// were made from are kept, rather than just the address of their map, so
+	// that the address can't be reused by a new map.
+	cacheSource    map[string]endpoint.Endpoint
+	cacheVersion   uint64
+	cacheEndpoints []endpoint.Endpoint
+	cacheInstances map[string]endpoint.Endpoint
+}
+
+type state struct {
+	healthy   bool
+	failures  int // consecutive
+	successes int // consecutive
+}
+
+var (
+	_ sd.MetadataSubscriber = &Subscriber{}
+	_ sd.StatusSubscriber   = &Subscriber{}
+)
+
+// NewSubscriber returns a subscriber that yields the healthy endpoints of s,
+// according to the check, and starts checking them.
+func NewSubscriber(s sd.Subscriber, check Check, logger log.Logger, options ...SubscriberOption) *Subscriber {
+	hs := newSubscriber(s, check, logger, options...)
+	go hs.loop()
+	return hs
+}
+
+func newSubscriber(s sd.Subscriber, check Check, logger log.Logger, options ...SubscriberOption) *Subscriber {
+	hs := &Subscriber{
+		s:        s,
+		check:    check,
+		logger:   logger,
+		interval: 10 * time.Second,
+		timeout:  time.Second,
+		fall:     3,
+		rise:     2,
+		quitc:    make(chan struct{}),
+		states:   map[string]*state{},
+	}
+	for _, option := range options {
+		option(hs)
+	}
+	return hs
+}
+
+// SubscriberOption sets an optional parameter for the Subscriber.
+type SubscriberOption func(*Subscriber)
+
+// Interval sets the time between checks of each instance. The default is 10
+// seconds.
+func Interval(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.interval = d }
+}
+
+// Timeout sets the time after which a check is canceled, and fails. The
+// default is 1 second.
+func Timeout(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.timeout = d }
+}
+
+// UnhealthyThreshold sets the number of consecutive failed checks after which
+// a healthy instance is considered unhealthy. The default is 3.
+func UnhealthyThreshold(n int) SubscriberOption {
+	return func(s *Subscriber) { s.fall = n }
+}
+
+// HealthyThreshold sets the number of consecutive successful checks after
+// which an unhealthy instance is considered healthy again. The default is 2.
+func HealthyThreshold(n int) SubscriberOption {
+	return func(s *Subscriber) { s.rise = n }
+}
+
+// Endpoints implements the Subscriber interface. Endpoints are ordered
+// lexicographically by the corresponding instance string.
+func (s *Subscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	if err := s.update(); err != nil {
+		return nil, err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.cacheEndpoints, nil
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (s *Subscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	if err := s.update(); err != nil {
+		return nil, err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.cacheInstances, nil
+}
+
+// Metadata implements the MetadataSubscriber interface. It passes on the
+// metadata of the wrapped subscriber, if it has any, including that of
+// unhealthy instances. Otherwise, it returns nil.
+func (s *Subscriber) Metadata() (map[string]sd.Instance, error) {
+	if ms, ok := s.s.(sd.MetadataSubscriber); ok {
+		return ms.Metadata()
+	}
+	return nil, nil
+}
+
+// Status implements the StatusSubscriber interface. It passes on the status
+// of the wrapped subscriber, if it reports any.
+func (s *Subscriber) Status() sd.Status {
+	if ss, ok := s.s.(sd.StatusSubscriber); ok {
+		return ss.Status()
+	}
+	return sd.Status{}
+}
+
+// Stop terminates the health checks. It doesn't stop the wrapped subscriber.
+// Stopping a stopped subscriber has no effect.
+func (s *Subscriber) Stop() {
+	s.stopOnce.Do(func() { close(s.quitc) })
+}
+
+// update refreshes the cached results, if the instances of the wrapped
+// subscriber or their health have changed.
+func (s *Subscriber) update() error {
+	instances, err := s.instances()
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.cacheInstances != nil && identity.Same(instances, s.cacheSource) && s.version == s.cacheVersion {
+		return nil
+	}
+
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		if st, ok := s.states[name]; ok && !st.healthy {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s.cacheSource, s.cacheVersion = instances, s.version
+	s.cacheEndpoints = make([]endpoint.Endpoint, len(names))
+	s.cacheInstances = make(map[string]endpoint.Endpoint, len(names))
+	for i, name := range names {
+		s.cacheEndpoints[i] = instances[name]
+		s.cacheInstances[name] = instances[name]
+	}
+	return nil
+}
+
+// instances returns the endpoints of the wrapped subscriber, keyed by
+// instance string, or by position if it isn't an InstanceSubscriber.
+func (s *Subscriber) instances() (map[string]endpoint.Endpoint, error) {
+	if is, ok := s.s.(sd.InstanceSubscriber); ok {
+		return is.Instances()
+	}
+	endpoints, err := s.s.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.keyed == nil || !identity.Same(endpoints, s.keyedSource) {
+		s.keyed = make(map[string]endpoint.Endpoint, len(endpoints))
+		for j, e := range endpoints {
+			s.keyed[fmt.Sprintf("#%d", j)] = e
+		}
+		s.keyedSource = endpoints
+	}
+	return s.keyed, nil
+}
+
+func (s *Subscriber) loop() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.checkAll()
+		select {
+		case <-ticker.C:
+		case <-s.quitc:
+			return
+		}
+	}
+}
+
+// checkAll checks every current instance concurrently, and waits for the
+// results.
+func (s *Subscriber) checkAll() {
+	instances, err := s.instances()
+	if err != nil {
+		s.logger.Log("err", err)
+		return
+	}
+
+	// Forget instances that have gone away.
+	s.mtx.Lock()
+	for name := range s.states {
+		if _, ok := instances[name]; !ok {
+			delete(s.states, name)
+		}
+	}
+	s.mtx.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(len(instances))
+	for name, e := range instances {
+		go func(name string, e endpoint.Endpoint) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+			defer cancel()
+			s.record(name, s.check(ctx, name, e))
+		}(name, e)
+	}
+	wg.Wait()
+}
+
+func (s *Subscriber) record(instance string, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	st, ok := s.states[instance]
+	if !ok {
+		st = &state{healthy: true}
+		s.states[instance] = st
+	}
+
+	if err != nil {
+		st.failures++
+		st.successes = 0
+		if st.healthy && st.failures >= s.fall {
+			st.healthy = false
+			s.version++
+			s.logger.Log("instance", instance, "healthy", false, "err", err)
+		}
+		return
+	}
+
+	st.successes++
+	st.failures = 0
+	if !st.healthy && st.successes >= s.rise {
+		st.healthy = true
+		s.version++
+		s.logger.Log("instance", instance, "healthy", true)
+	}
+}
@@ -0,0 +1,168 @@
+package healthcheck
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestSubscriberThresholds(t *testing.T) {
+	var (
+		check      = &fakeCheck{failing: map[string]bool{}}
+		subscriber = sd.FixedInstances(map[string]endpoint.Endpoint{"a": endpoint.Nop, "b": endpoint.Nop})
+		s          = newSubscriber(subscriber, check.Check, log.NewNopLogger(), UnhealthyThreshold(2), HealthyThreshold(2))
+	)
+
+	// New instances are healthy until checked.
+	assertHealthy(t, s, 2)
+	s.checkAll()
+	assertHealthy(t, s, 2)
+
+	// A single failure isn't enough to remove an instance.
+	check.set("b", true)
+	s.checkAll()
+	assertHealthy(t, s, 2)
+	s.checkAll()
+	assertHealthy(t, s, 1)
+
+	// Nor is a single success enough to restore it.
+	check.set("b", false)
+	s.checkAll()
+	assertHealthy(t, s, 1)
+	s.checkAll()
+	assertHealthy(t, s, 2)
+}
+
+func TestSubscriberStableEndpoints(t *testing.T) {
+	s := newSubscriber(sd.FixedInstances(map[string]endpoint.Endpoint{"a": endpoint.Nop}), (&fakeCheck{}).Check, log.NewNopLogger())
+	first, _ := s.Endpoints()
+	second, _ := s.Endpoints()
+	if &first[0] != &second[0] {
+		t.Errorf("want the same slice, have a new one")
+	}
+}
+
+func TestSubscriberLoop(t *testing.T) {
+	check := &fakeCheck{failing: map[string]bool{"a": true}}
+	s := NewSubscriber(sd.FixedInstances(map[string]endpoint.Endpoint{"a": endpoint.Nop}), check.Check, log.NewNopLogger(), Interval(time.Millisecond), UnhealthyThreshold(1))
+	defer s.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		endpoints, _ := s.Endpoints()
+		if len(endpoints) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("instance not removed in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscriberByPosition(t *testing.T) {
+	var (
+		check = &fakeCheck{failing: map[string]bool{"#1": true}}
+		s     = newSubscriber(sd.FixedSubscriber{endpoint.Nop, endpoint.Nop}, check.Check, log.NewNopLogger(), UnhealthyThreshold(1))
+	)
+	s.checkAll()
+	assertHealthy(t, s, 1)
+	instances, err := s.Instances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := instances["#0"]; !ok {
+		t.Errorf("want #0, have %v", instances)
+	}
+
+	metadata, err := s.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata != nil {
+		t.Errorf("want no metadata, have %v", metadata)
+	}
+}
+
+func TestSubscriberStopTwice(t *testing.T) {
+	s := NewSubscriber(sd.FixedInstances(map[string]endpoint.Endpoint{}), (&fakeCheck{}).Check, log.NewNopLogger())
+	s.Stop()
+	s.Stop() // doesn't panic
+}
+
+func TestDialCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := DialCheck("tcp")(ctx, addr, endpoint.Nop); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+
+	ln.Close()
+	if err := DialCheck("tcp")(ctx, addr, endpoint.Nop); err == nil {
+		t.Error("want error, have none")
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	instance := strings.TrimPrefix(server.URL, "http://")
+
+	if err := HTTPCheck(nil, "/health")(context.Background(), instance, endpoint.Nop); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+	if err := HTTPCheck(nil, "/other")(context.Background(), instance, endpoint.Nop); err == nil {
+		t.Error("want error, have none")
+	}
+}
+
+func assertHealthy(t *testing.T, s *Subscriber, want int) {
+	endpoints, err := s.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := len(endpoints); want != have {
+		t.Errorf("want %d healthy, have %d", want, have)
+	}
+}
+
+type fakeCheck struct {
+	mtx     sync.Mutex
+	failing map[string]bool
+}
+
+func (c *fakeCheck) set(instance string, failing bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.failing[instance] = failing
+}
+
+func (c *fakeCheck) Check(_ context.Context, instance string, _ endpoint.Endpoint) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.failing[instance] {
+		return errors.New("unhealthy")
+	}
+	return nil
+}
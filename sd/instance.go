@@ -0,0 +1,32 @@
+package sd
+
+// Instance describes a single instance of a service. Addr is the instance
+// string passed to the Factory, typically host:port. The other fields are
+// optional metadata; subscribers fill in whatever their service discovery
+// system provides, and leave the rest empty.
+type Instance struct {
+	Addr     string   // e.g. 10.0.2.10:80
+	Weight   int      // relative share of requests; zero means the default of 1
+	Priority int      // as in DNS SRV records: lower is preferred; zero is the highest
+	Zone     string   // locality, e.g. an availability zone or datacenter
+	Tags     []string // e.g. Consul service tags
+}
+
+// EffectiveWeight returns the weight of the instance, substituting the
+// default of 1 for an unset or negative weight.
+func (i Instance) EffectiveWeight() int {
+	if i.Weight <= 0 {
+		return 1
+	}
+	return i.Weight
+}
+
+// HasTag returns true if the instance carries the given tag.
+func (i Instance) HasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
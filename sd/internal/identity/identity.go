@@ -0,0 +1,26 @@
+// Package identity tells whether the maps and slices yielded by subscribers
+// are the ones seen before. Subscribers yield the same map or slice for as
+// long as their instances don't change, so anything derived from a result
+// only needs to be rebuilt when a different one is yielded.
+package identity
+
+import "reflect"
+
+// Same reports whether a and b are the same map, or the same slice, of the
+// same type. Addresses are only compared soundly while both are reachable,
+// as memory is reused once it isn't, so callers must keep the map or slice
+// they compare against, rather than just its address.
+func Same(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Map:
+		return va.Pointer() == vb.Pointer()
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,27 @@
+package identity
+
+import "testing"
+
+func TestSame(t *testing.T) {
+	var (
+		m = map[string]int{"a": 1}
+		s = []int{1, 2, 3}
+	)
+	for _, tc := range []struct {
+		a, b interface{}
+		want bool
+	}{
+		{m, m, true},
+		{m, map[string]int{"a": 1}, false},
+		{m, map[string]string{}, false},
+		{s, s, true},
+		{s, s[:2], false},
+		{s, []int{1, 2, 3}, false},
+		{m, nil, false},
+		{1, 1, false},
+	} {
+		if want, have := tc.want, Same(tc.a, tc.b); want != have {
+			t.Errorf("Same(%v, %v): want %v, have %v", tc.a, tc.b, want, have)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// ErrExpired is returned by a Watch when the API server reports that the
+// requested resource version is too old to watch from. Callers should list
+// the resource again and start a new watch from the returned version.
+var ErrExpired = errors.New("resource version expired")
+
+// Client is a minimal wrapper around the Kubernetes API, covering just the
+// calls needed to follow the Endpoints of a single service.
+type Client interface {
+	// Endpoints returns the current Endpoints object of the named service.
+	Endpoints(ctx context.Context, namespace, service string) (Endpoints, error)
+
+	// Watch streams changes to the Endpoints object of the named service,
+	// starting after the given resource version. The API server ends the
+	// watch after the timeout, rounded up to whole seconds, unless it's zero.
+	// The watch stops when the context is canceled, and should be closed by
+	// the caller.
+	Watch(ctx context.Context, namespace, service, resourceVersion string, timeout time.Duration) (Watch, error)
+}
+
+// Watch is a stream of events produced by Client.Watch.
+type Watch interface {
+	// Next blocks until the next event arrives. It returns io.EOF when the
+	// API server ends the watch, which it does periodically.
+	Next() (Event, error)
+
+	// Close terminates the watch.
+	Close() error
+}
+
+// Event is a single change to an Endpoints object. Type is one of ADDED,
+// MODIFIED or DELETED.
+type Event struct {
+	Type   string    `json:"type"`
+	Object Endpoints `json:"object"`
+}
+
+// Endpoints mirrors the parts of the Kubernetes v1 Endpoints resource that
+// are relevant to service discovery.
+type Endpoints struct {
+	Metadata ObjectMeta       `json:"metadata"`
+	Subsets  []EndpointSubset `json:"subsets"`
+}
+
+// ObjectMeta is the metadata common to all Kubernetes objects.
+type ObjectMeta struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// EndpointSubset is a group of addresses sharing a set of ports. Only ready
+// addresses are included; Kubernetes reports the rest separately, as
+// notReadyAddresses, which are deliberately ignored.
+type EndpointSubset struct {
+	Addresses []EndpointAddress `json:"addresses"`
+	Ports     []EndpointPort    `json:"ports"`
+}
+
+// EndpointAddress is a single ready IP address of a service.
+type EndpointAddress struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname,omitempty"`
+	NodeName string `json:"nodeName,omitempty"`
+}
+
+// EndpointPort is a port exposed by the addresses of a subset.
+type EndpointPort struct {
+	Name     string `json:"name"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+type client struct {
+	host   string
+	token  string
+	client *http.Client
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*client)
+
+// ClientToken sets the bearer token used to authenticate against the API
+// server. By default, requests are unauthenticated.
+func ClientToken(token string) ClientOption {
+	return func(c *client) { c.token = token }
+}
+
+// ClientHTTPClient sets the HTTP client used for requests, e.g. to configure
+// TLS. The client mustn't have a timeout, as watches are long-lived. By
+// default, http.DefaultClient is used.
+func ClientHTTPClient(hc *http.Client) ClientOption {
+	return func(c *client) { c.client = hc }
+}
+
+// NewClient returns a Client talking to the API server at host, which should
+// be a base URL like "https://10.0.0.1:443".
+func NewClient(host string, options ...ClientOption) Client {
+	c := &client{
+		host:   strings.TrimRight(host, "/"),
+		client: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+
+// NewInClusterClient returns a Client configured from the environment and the
+// service account credentials Kubernetes mounts into every pod.
+func NewInClusterClient() (Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster")
+	}
+	token, err := ioutil.ReadFile(serviceAccountDir + "token")
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates found in service account CA")
+	}
+	hc := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+	return NewClient(
+		"https://"+net.JoinHostPort(host, port),
+		ClientToken(strings.TrimSpace(string(token))),
+		ClientHTTPClient(hc),
+	), nil
+}
+
+func (c *client) Endpoints(ctx context.Context, namespace, service string) (Endpoints, error) {
+	var e Endpoints
+	resp, err := c.get(ctx, fmt.Sprintf("/api/v1/namespaces/%s/endpoints/%s", namespace, service), nil)
+	if err != nil {
+		return e, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&e)
+	return e, err
+}
+
+func (c *client) Watch(ctx context.Context, namespace, service, resourceVersion string, timeout time.Duration) (Watch, error) {
+	query := url.Values{
+		"watch":           {"true"},
+		"fieldSelector":   {"metadata.name=" + service},
+		"resourceVersion": {resourceVersion},
+	}
+	if timeout > 0 {
+		seconds := (timeout + time.Second - 1) / time.Second
+		query.Set("timeoutSeconds", strconv.Itoa(int(seconds)))
+	}
+	resp, err := c.get(ctx, fmt.Sprintf("/api/v1/namespaces/%s/endpoints", namespace), query)
+	if err != nil {
+		return nil, err
+	}
+	return &watch{body: resp.Body, dec: json.NewDecoder(resp.Body)}, nil
+}
+
+func (c *client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := c.host + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := ctxhttp.Do(ctx, c.client, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, decodeStatus(resp.StatusCode, resp.Body)
+	}
+	return resp, nil
+}
+
+type watch struct {
+	body io.Closer
+	dec  *json.Decoder
+}
+
+func (w *watch) Next() (Event, error) {
+	var raw struct {
+		Type   string          `json:"type"`
+		Object json.RawMessage `json:"object"`
+	}
+	if err := w.dec.Decode(&raw); err != nil {
+		return Event{}, err
+	}
+	if raw.Type == "ERROR" {
+		var s status
+		if err := json.Unmarshal(raw.Object, &s); err != nil {
+			return Event{}, err
+		}
+		return Event{}, s.err()
+	}
+	e := Event{Type: raw.Type}
+	err := json.Unmarshal(raw.Object, &e.Object)
+	return e, err
+}
+
+func (w *watch) Close() error {
+	return w.body.Close()
+}
+
+// status is the Kubernetes Status object, returned for failed requests and
+// as the payload of ERROR watch events.
+type status struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (s status) err() error {
+	if s.Code == http.StatusGone || s.Reason == "Expired" || s.Reason == "Gone" {
+		return ErrExpired
+	}
+	return fmt.Errorf("kubernetes: %d %s: %s", s.Code, s.Reason, s.Message)
+}
+
+func decodeStatus(code int, body io.Reader) error {
+	s := status{Code: code}
+	if err := json.NewDecoder(body).Decode(&s); err != nil || s.Code == 0 {
+		s.Code = code
+	}
+	if s.Reason == "" {
+		s.Reason = http.StatusText(code)
+	}
+	return s.err()
+}
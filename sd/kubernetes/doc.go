@@ -0,0 +1,4 @@
+// Package kubernetes provides a subscriber for services running in
+// Kubernetes. It follows the Endpoints object of a service via the plain REST
+// watch API, so it needs no client library beyond net/http.
+package kubernetes
@@ -0,0 +1,250 @@
+package kubernetes
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/cache"
+)
+
+// DefaultWatchTimeout is the default time after which a Subscriber asks the
+// API server to end a watch.
+const DefaultWatchTimeout = 5 * time.Minute
+
+// Subscriber yields endpoints for a service in Kubernetes, taken from the
+// service's Endpoints object. The object is watched, and every change is
+// reflected in the Subscriber endpoints.
+//
+// Watches are bounded by a timeout, which the API server enforces by ending
+// them. If it fails to, e.g. because the connection is half-open, the
+// Subscriber abandons the watch shortly after, and lists the object again.
+type Subscriber struct {
+	cache         *cache.Cache
+	client        Client
+	logger        log.Logger
+	namespace     string
+	service       string
+	portName      string
+	retryInterval time.Duration
+	watchTimeout  time.Duration
+	cacheOptions  []cache.Option
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+var (
+	_ sd.MetadataSubscriber  = &Subscriber{}
+	_ sd.StatusSubscriber    = &Subscriber{}
+	_ sd.NotifyingSubscriber = &Subscriber{}
+)
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberPortName restricts the Subscriber to the named port of the
+// service. By default, every port of every address becomes an instance, which
+// is only sensible for services exposing a single port.
+func SubscriberPortName(name string) SubscriberOption {
+	return func(s *Subscriber) { s.portName = name }
+}
+
+// SubscriberRetryInterval sets how long the Subscriber waits before retrying
+// after a failed request to the API server. By default, one second.
+func SubscriberRetryInterval(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.retryInterval = d }
+}
+
+// SubscriberWatchTimeout sets the time after which the API server is asked
+// to end a watch, which the Subscriber then resumes. Zero leaves watches
+// unbounded. By default, DefaultWatchTimeout.
+func SubscriberWatchTimeout(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.watchTimeout = d }
+}
+
+// SubscriberCacheOptions configures the endpoint cache of the Subscriber.
+func SubscriberCacheOptions(options ...cache.Option) SubscriberOption {
+	return func(s *Subscriber) { s.cacheOptions = append(s.cacheOptions, options...) }
+}
+
+// NewSubscriber returns a Kubernetes subscriber which returns endpoints for
+// the ready addresses of the named service in the given namespace.
+func NewSubscriber(client Client, factory sd.Factory, logger log.Logger, namespace, service string, options ...SubscriberOption) *Subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Subscriber{
+		client:        client,
+		logger:        log.NewContext(logger).With("namespace", namespace, "service", service),
+		namespace:     namespace,
+		service:       service,
+		retryInterval: time.Second,
+		watchTimeout:  DefaultWatchTimeout,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.cache = cache.New(factory, s.logger, s.cacheOptions...)
+
+	version, err := s.list()
+	if err != nil {
+		s.logger.Log("err", err)
+		s.cache.SetError(err)
+	}
+
+	go s.loop(version)
+	return s
+}
+
+// Endpoints implements the Subscriber interface.
+func (s *Subscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	return s.cache.Endpoints()
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (s *Subscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.cache.Instances()
+}
+
+// Metadata implements the MetadataSubscriber interface.
+func (s *Subscriber) Metadata() (map[string]sd.Instance, error) {
+	return s.cache.Metadata(), nil
+}
+
+// Status implements the StatusSubscriber interface.
+func (s *Subscriber) Status() sd.Status {
+	return s.cache.Status()
+}
+
+// Notify implements the NotifyingSubscriber interface.
+func (s *Subscriber) Notify(ch chan<- sd.Event) {
+	s.cache.Notify(ch)
+}
+
+// StopNotify implements the NotifyingSubscriber interface.
+func (s *Subscriber) StopNotify(ch chan<- sd.Event) {
+	s.cache.StopNotify(ch)
+}
+
+// Stop terminates the subscriber.
+func (s *Subscriber) Stop() {
+	s.cancel()
+}
+
+func (s *Subscriber) loop(version string) {
+	var err error
+	for {
+		if version == "" {
+			version, err = s.list()
+		}
+		if err == nil {
+			version, err = s.watch(version)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		default:
+		}
+
+		switch err {
+		case io.EOF:
+			// The API server ends watches after a while. Resume from the last
+			// version we've seen.
+			err = nil
+		case ErrExpired:
+			// We've fallen too far behind to resume. Start over.
+			version, err = "", nil
+		case errWatchTimeout:
+			// The API server didn't end the watch, so the connection may
+			// have been lost without us noticing. Events may have been
+			// missed, so start over.
+			s.logger.Log("msg", "watch not ended by the API server, listing again")
+			version, err = "", nil
+		default:
+			// Start over, so that the error is cleared as soon as we're
+			// back in sync.
+			s.logger.Log("err", err)
+			s.cache.SetError(err)
+			version, err = "", nil
+			select {
+			case <-time.After(s.retryInterval):
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// list fetches the current state of the service, and returns the version to
+// watch from. If it fails, the cache isn't updated, so potentially-good
+// endpoints aren't replaced with nothing.
+func (s *Subscriber) list() (string, error) {
+	e, err := s.client.Endpoints(s.ctx, s.namespace, s.service)
+	if err != nil {
+		return "", err
+	}
+	instances := makeInstances(e, s.portName)
+	s.logger.Log("instances", len(instances))
+	s.cache.Update(instances)
+	return e.Metadata.ResourceVersion, nil
+}
+
+// errWatchTimeout is returned by watch if the watch outlived its timeout.
+var errWatchTimeout = errors.New("watch timed out")
+
+// watch applies changes to the service until the watch ends, and returns the
+// last version seen. The watch is abandoned if the API server doesn't end it
+// within a quarter of its timeout after it's due.
+func (s *Subscriber) watch(version string) (string, error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	if s.watchTimeout > 0 {
+		ctx, cancel = context.WithTimeout(s.ctx, s.watchTimeout+s.watchTimeout/4)
+	}
+	defer cancel()
+
+	w, err := s.client.Watch(ctx, s.namespace, s.service, version, s.watchTimeout)
+	if err != nil {
+		return version, err
+	}
+	defer w.Close()
+
+	for {
+		event, err := w.Next()
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				err = errWatchTimeout
+			}
+			return version, err
+		}
+		switch event.Type {
+		case "DELETED":
+			s.cache.Update(nil)
+		default:
+			s.cache.Update(makeInstances(event.Object, s.portName))
+		}
+		version = event.Object.Metadata.ResourceVersion
+	}
+}
+
+func makeInstances(e Endpoints, portName string) []string {
+	var instances []string
+	for _, subset := range e.Subsets {
+		for _, port := range subset.Ports {
+			if portName != "" && port.Name != portName {
+				continue
+			}
+			for _, addr := range subset.Addresses {
+				instances = append(instances, net.JoinHostPort(addr.IP, strconv.Itoa(port.Port)))
+			}
+		}
+	}
+	return instances
+}
@@ -0,0 +1,344 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+func TestSubscriber(t *testing.T) {
+	api := newFakeAPI(endpointsObject("1", "10.0.0.1", "10.0.0.2"))
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL), factory, log.NewNopLogger(), "default", "search")
+	defer s.Stop()
+
+	assertInstances(t, s, "10.0.0.1:8080", "10.0.0.2:8080")
+	waitFor(t, func() bool { return api.lastWatchVersion() == "1" && api.watching() })
+
+	api.push("MODIFIED", endpointsObject("2", "10.0.0.1", "10.0.0.2", "10.0.0.3"))
+	assertInstances(t, s, "10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.3:8080")
+
+	api.push("DELETED", endpointsObject("3"))
+	assertInstances(t, s)
+}
+
+func TestSubscriberResumesWatch(t *testing.T) {
+	api := newFakeAPI(endpointsObject("1", "10.0.0.1"))
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL), factory, log.NewNopLogger(), "default", "search")
+	defer s.Stop()
+
+	api.push("MODIFIED", endpointsObject("2", "10.0.0.2"))
+	assertInstances(t, s, "10.0.0.2:8080")
+
+	// The API server ends the watch; the subscriber should resume from the
+	// last version it saw, without listing again.
+	api.end()
+	waitFor(t, func() bool { return api.lastWatchVersion() == "2" && api.watching() })
+	if want, have := 1, api.listCount(); want != have {
+		t.Errorf("want %d lists, have %d", want, have)
+	}
+}
+
+func TestSubscriberRelistsWhenExpired(t *testing.T) {
+	api := newFakeAPI(endpointsObject("1", "10.0.0.1"))
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL), factory, log.NewNopLogger(), "default", "search")
+	defer s.Stop()
+	assertInstances(t, s, "10.0.0.1:8080")
+
+	api.set(endpointsObject("7", "10.0.0.7"))
+	api.pushRaw(`{"type":"ERROR","object":{"kind":"Status","code":410,"reason":"Expired","message":"too old resource version"}}`)
+	assertInstances(t, s, "10.0.0.7:8080")
+	waitFor(t, func() bool { return api.lastWatchVersion() == "7" && api.watching() })
+}
+
+func TestSubscriberRelistsAfterWatchTimeout(t *testing.T) {
+	api := newFakeAPI(endpointsObject("1", "10.0.0.1"))
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	// The fake API server never ends watches, as if the connection was lost.
+	s := NewSubscriber(NewClient(server.URL), factory, log.NewNopLogger(), "default", "search", SubscriberWatchTimeout(20*time.Millisecond))
+	defer s.Stop()
+
+	waitFor(t, func() bool { return api.listCount() >= 2 })
+	if want, have := "1", api.lastWatchTimeout(); want != have {
+		t.Errorf("want timeoutSeconds %s, have %s", want, have)
+	}
+}
+
+func TestSubscriberRetriesFailedList(t *testing.T) {
+	api := newFakeAPI(endpointsObject("1", "10.0.0.1"))
+	api.failLists(2)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	s := NewSubscriber(NewClient(server.URL), factory, log.NewNopLogger(), "default", "search", SubscriberRetryInterval(time.Millisecond))
+	defer s.Stop()
+
+	assertInstances(t, s, "10.0.0.1:8080")
+	if want, have := 3, api.listCount(); want != have {
+		t.Errorf("want %d lists, have %d", want, have)
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	api := newFakeAPI(endpointsObject("1", "10.0.0.1"))
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	if _, err := NewClient(server.URL).Endpoints(context.Background(), "default", "search"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "", api.lastAuthorization(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if _, err := NewClient(server.URL, ClientToken("s3cr3t")).Endpoints(context.Background(), "default", "search"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "Bearer s3cr3t", api.lastAuthorization(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if _, err := NewClient(server.URL).Endpoints(context.Background(), "default", "nonexistent"); err == nil {
+		t.Error("want error for unknown service, have none")
+	}
+}
+
+func TestMakeInstances(t *testing.T) {
+	e := Endpoints{
+		Subsets: []EndpointSubset{
+			{
+				Addresses: []EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}},
+				Ports:     []EndpointPort{{Name: "http", Port: 8080}, {Name: "grpc", Port: 8081}},
+			},
+			{
+				Addresses: []EndpointAddress{{IP: "fd00::3"}},
+				Ports:     []EndpointPort{{Name: "http", Port: 9090}},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		portName string
+		want     []string
+	}{
+		{"", []string{"10.0.0.1:8080", "10.0.0.1:8081", "10.0.0.2:8080", "10.0.0.2:8081", "[fd00::3]:9090"}},
+		{"http", []string{"10.0.0.1:8080", "10.0.0.2:8080", "[fd00::3]:9090"}},
+		{"grpc", []string{"10.0.0.1:8081", "10.0.0.2:8081"}},
+		{"metrics", nil},
+	} {
+		have := makeInstances(e, tc.portName)
+		sort.Strings(have)
+		if strings.Join(tc.want, ",") != strings.Join(have, ",") {
+			t.Errorf("port %q: want %v, have %v", tc.portName, tc.want, have)
+		}
+	}
+}
+
+func factory(instance string) (endpoint.Endpoint, io.Closer, error) {
+	return endpoint.Nop, nil, nil
+}
+
+func endpointsObject(version string, ips ...string) Endpoints {
+	e := Endpoints{Metadata: ObjectMeta{Name: "search", Namespace: "default", ResourceVersion: version}}
+	if len(ips) > 0 {
+		subset := EndpointSubset{Ports: []EndpointPort{{Name: "http", Port: 8080, Protocol: "TCP"}}}
+		for _, ip := range ips {
+			subset.Addresses = append(subset.Addresses, EndpointAddress{IP: ip})
+		}
+		e.Subsets = []EndpointSubset{subset}
+	}
+	return e
+}
+
+func assertInstances(t *testing.T, s *Subscriber, want ...string) {
+	var have []string
+	ok := waitUntil(func() bool {
+		instances, _ := s.Instances()
+		have = have[:0]
+		for instance := range instances {
+			have = append(have, instance)
+		}
+		sort.Strings(have)
+		return strings.Join(want, ",") == strings.Join(have, ",")
+	})
+	if !ok {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+}
+
+func waitFor(t *testing.T, f func() bool) {
+	if !waitUntil(f) {
+		t.Fatal("timed out")
+	}
+}
+
+func waitUntil(f func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// fakeAPI is a minimal Kubernetes API server, serving a single Endpoints
+// object and streaming whatever events are pushed to its watchers.
+type fakeAPI struct {
+	mtx       sync.Mutex
+	current   Endpoints
+	lists     int
+	listFails int
+	version   string
+	timeout   string
+	auth      string
+	active    int
+	events    chan string
+}
+
+func newFakeAPI(e Endpoints) *fakeAPI {
+	return &fakeAPI{
+		current: e,
+		events:  make(chan string),
+	}
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mtx.Lock()
+	a.auth = r.Header.Get("Authorization")
+	a.mtx.Unlock()
+
+	switch {
+	case r.URL.Path == "/api/v1/namespaces/default/endpoints/search":
+		a.list(w)
+	case r.URL.Path == "/api/v1/namespaces/default/endpoints" && r.URL.Query().Get("watch") == "true":
+		if want, have := "metadata.name=search", r.URL.Query().Get("fieldSelector"); want != have {
+			http.Error(w, "bad field selector "+have, http.StatusBadRequest)
+			return
+		}
+		a.watch(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind":"Status","code":404,"reason":"NotFound","message":"not found"}`))
+	}
+}
+
+func (a *fakeAPI) list(w http.ResponseWriter) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.lists++
+	if a.listFails > 0 {
+		a.listFails--
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	json.NewEncoder(w).Encode(a.current)
+}
+
+func (a *fakeAPI) watch(w http.ResponseWriter, r *http.Request) {
+	a.mtx.Lock()
+	a.version = r.URL.Query().Get("resourceVersion")
+	a.timeout = r.URL.Query().Get("timeoutSeconds")
+	a.active++
+	a.mtx.Unlock()
+	defer func() {
+		a.mtx.Lock()
+		a.active--
+		a.mtx.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	closed := w.(http.CloseNotifier).CloseNotify()
+	for {
+		select {
+		case event, ok := <-a.events:
+			if !ok || event == "" {
+				return
+			}
+			w.Write([]byte(event + "\n"))
+			w.(http.Flusher).Flush()
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (a *fakeAPI) push(typ string, e Endpoints) {
+	a.set(e)
+	buf, _ := json.Marshal(Event{Type: typ, Object: e})
+	a.pushRaw(string(buf))
+}
+
+func (a *fakeAPI) pushRaw(event string) {
+	a.events <- event
+}
+
+// end terminates the current watch, as the API server does periodically.
+func (a *fakeAPI) end() {
+	a.events <- ""
+}
+
+func (a *fakeAPI) set(e Endpoints) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.current = e
+}
+
+func (a *fakeAPI) failLists(n int) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.listFails = n
+}
+
+func (a *fakeAPI) listCount() int {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.lists
+}
+
+func (a *fakeAPI) lastWatchVersion() string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.version
+}
+
+func (a *fakeAPI) lastWatchTimeout() string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.timeout
+}
+
+func (a *fakeAPI) lastAuthorization() string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.auth
+}
+
+func (a *fakeAPI) watching() bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.active > 0
+}
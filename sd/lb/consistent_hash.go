@@ -0,0 +1,128 @@
+package lb
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/identity"
+)
+
+// KeyedBalancer yields endpoints according to a routing key, such that
+// requests with the same key are routed to the same endpoint.
+type KeyedBalancer interface {
+	Endpoint(key string) (endpoint.Endpoint, error)
+}
+
+// KeyFunc extracts a routing key from a request, or from its context, e.g. a
+// user ID or a cache key.
+type KeyFunc func(ctx context.Context, request interface{}) string
+
+// Keyed returns an endpoint that invokes the endpoint yielded by the balancer
+// for the key of each request.
+func Keyed(b KeyedBalancer, key KeyFunc) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		e, err := b.Endpoint(key(ctx, request))
+		if err != nil {
+			return nil, err
+		}
+		return e(ctx, request)
+	}
+}
+
+// NewConsistentHash returns a keyed load balancer that maps keys onto a
+// consistent hash ring of the instances yielded by the subscriber. Each
+// instance is placed on the ring replicas times; more replicas spread keys
+// more evenly, at the cost of memory. When an instance goes away, only the
+// keys that mapped to it are moved, and when one is added, it only takes keys
+// from the others.
+func NewConsistentHash(s sd.InstanceSubscriber, replicas int) KeyedBalancer {
+	if replicas <= 0 {
+		replicas = 1
+	}
+	return &consistentHash{
+		s:        s,
+		replicas: replicas,
+	}
+}
+
+type consistentHash struct {
+	s        sd.InstanceSubscriber
+	replicas int
+
+	mtx  sync.Mutex
+	ring *ring
+}
+
+// ring is built from a single set of instances, which is identified by the
+// map holding them; subscribers yield a new map when their instances change.
+// The ring keeps the map, so that its address can't be reused by a new one.
+type ring struct {
+	instances map[string]endpoint.Endpoint
+	hashes    []uint32 // sorted
+	endpoints map[uint32]endpoint.Endpoint
+}
+
+func (ch *consistentHash) Endpoint(key string) (endpoint.Endpoint, error) {
+	instances, err := ch.s.Instances()
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	r := ch.current(instances)
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0 // wrap around
+	}
+	return r.endpoints[r.hashes[i]], nil
+}
+
+func (ch *consistentHash) current(instances map[string]endpoint.Endpoint) *ring {
+	ch.mtx.Lock()
+	defer ch.mtx.Unlock()
+	if ch.ring != nil && identity.Same(ch.ring.instances, instances) {
+		return ch.ring
+	}
+
+	// Place instances in a deterministic order, so that hash collisions are
+	// resolved the same way by every client.
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r := &ring{
+		instances: instances,
+		hashes:    make([]uint32, 0, len(names)*ch.replicas),
+		endpoints: make(map[uint32]endpoint.Endpoint, len(names)*ch.replicas),
+	}
+	for _, name := range names {
+		for i := 0; i < ch.replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + name))
+			if _, ok := r.endpoints[h]; ok {
+				continue
+			}
+			r.hashes = append(r.hashes, h)
+			r.endpoints[h] = instances[name]
+		}
+	}
+	sort.Sort(uint32s(r.hashes))
+	ch.ring = r
+	return r
+}
+
+type uint32s []uint32
+
+func (a uint32s) Len() int           { return len(a) }
+func (a uint32s) Less(i, j int) bool { return a[i] < a[j] }
+func (a uint32s) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,111 @@
+package lb
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestConsistentHash(t *testing.T) {
+	instances := map[string]endpoint.Endpoint{}
+	for _, name := range []string{"a:80", "b:80", "c:80", "d:80"} {
+		instances[name] = named(name)
+	}
+	before := route(t, NewConsistentHash(sd.FixedInstances(instances), 50))
+
+	// Every instance should get a share of the keys.
+	counts := map[string]int{}
+	for _, instance := range before {
+		counts[instance]++
+	}
+	for name := range instances {
+		if counts[name] == 0 {
+			t.Errorf("%s: no keys", name)
+		}
+	}
+
+	// Removing an instance should only move the keys that mapped to it.
+	delete(instances, "c:80")
+	after := route(t, NewConsistentHash(sd.FixedInstances(instances), 50))
+	for key, instance := range before {
+		if instance != "c:80" && after[key] != instance {
+			t.Errorf("%s: moved from %s to %s", key, instance, after[key])
+		}
+	}
+}
+
+func TestConsistentHashUpdate(t *testing.T) {
+	subscriber := &mutableInstanceSubscriber{instances: map[string]endpoint.Endpoint{"a:80": named("a:80")}}
+	balancer := NewConsistentHash(subscriber, 10)
+	if want, have := "a:80", invoke(t, balancer, "key"); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	subscriber.instances = map[string]endpoint.Endpoint{"b:80": named("b:80")}
+	if want, have := "b:80", invoke(t, balancer, "key"); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestConsistentHashNoEndpoints(t *testing.T) {
+	balancer := NewConsistentHash(sd.FixedInstances(map[string]endpoint.Endpoint{}), 10)
+	_, err := balancer.Endpoint("key")
+	if want, have := ErrNoEndpoints, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestKeyed(t *testing.T) {
+	balancer := NewConsistentHash(sd.FixedInstances(map[string]endpoint.Endpoint{"a:80": named("a:80"), "b:80": named("b:80")}), 10)
+	e := Keyed(balancer, func(_ context.Context, request interface{}) string { return request.(string) })
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		want := invoke(t, balancer, key)
+		have, err := e(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != have {
+			t.Errorf("%s: want %s, have %s", key, want, have)
+		}
+	}
+}
+
+// named returns an endpoint that responds with the passed name.
+func named(name string) endpoint.Endpoint {
+	return func(context.Context, interface{}) (interface{}, error) { return name, nil }
+}
+
+func invoke(t *testing.T, b KeyedBalancer, key string) string {
+	e, err := b.Endpoint(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, _ := e(context.Background(), struct{}{})
+	return response.(string)
+}
+
+func route(t *testing.T, b KeyedBalancer) map[string]string {
+	routes := map[string]string{}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		routes[key] = invoke(t, b, key)
+	}
+	return routes
+}
+
+type mutableInstanceSubscriber struct {
+	instances map[string]endpoint.Endpoint
+}
+
+func (s *mutableInstanceSubscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	return sd.FixedInstances(s.instances).Endpoints()
+}
+
+func (s *mutableInstanceSubscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.instances, nil
+}
@@ -0,0 +1,48 @@
+package lb
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// instancesOf returns the endpoints of s, as the map yielded by Instances if
+// s is an InstanceSubscriber, or the slice yielded by Endpoints otherwise.
+// Subscribers yield the same map or slice until their instances change, so
+// callers compare results with identity.Same to tell whether to rebuild.
+func instancesOf(s sd.Subscriber) (interface{}, error) {
+	if is, ok := s.(sd.InstanceSubscriber); ok {
+		return is.Instances()
+	}
+	return s.Endpoints()
+}
+
+// keyed returns the keys and endpoints of a result of instancesOf. Instances
+// are ordered by instance string. Endpoints of a slice are keyed by their
+// position, as "#j", and keep their order; the same key in a different slice
+// may well be a different instance.
+func keyed(instances interface{}) ([]string, []endpoint.Endpoint) {
+	switch x := instances.(type) {
+	case map[string]endpoint.Endpoint:
+		keys := make([]string, 0, len(x))
+		for key := range x {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		endpoints := make([]endpoint.Endpoint, len(keys))
+		for i, key := range keys {
+			endpoints[i] = x[key]
+		}
+		return keys, endpoints
+	case []endpoint.Endpoint:
+		keys := make([]string, len(x))
+		for j := range x {
+			keys[j] = fmt.Sprintf("#%d", j)
+		}
+		return keys, x
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,48 @@
+package lb
+
+import (
+	"sync/atomic"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// NewLeastOutstanding returns a load balancer that selects the endpoint with
+// the fewest requests in flight. Ties are broken by the lowest average
+// latency, and then in turn, so that idle endpoints share traffic equally.
+//
+// Requests are only tracked if they're made via the endpoints returned by the
+// balancer, so every caller should use the same balancer.
+func NewLeastOutstanding(s sd.Subscriber) Balancer {
+	return &leastOutstanding{
+		t: newLoadTracker(s),
+	}
+}
+
+type leastOutstanding struct {
+	t *loadTracker
+	c uint64
+}
+
+func (lo *leastOutstanding) Endpoint() (endpoint.Endpoint, error) {
+	loads, err := lo.t.current()
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the scan at a different offset each time, to rotate among ties.
+	offset := int((atomic.AddUint64(&lo.c, 1) - 1) % uint64(len(loads)))
+	best := loads[offset]
+	bestInFlight, bestCost := best.inFlight(), best.cost()
+	for i := 1; i < len(loads); i++ {
+		l := loads[(offset+i)%len(loads)]
+		inFlight := l.inFlight()
+		if inFlight > bestInFlight {
+			continue
+		}
+		if cost := l.cost(); inFlight < bestInFlight || cost < bestCost {
+			best, bestInFlight, bestCost = l, inFlight, cost
+		}
+	}
+	return best.endpoint, nil
+}
@@ -0,0 +1,144 @@
+package lb
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/cache"
+)
+
+func TestLeastOutstanding(t *testing.T) {
+	var (
+		counts    = make([]int, 3)
+		started   = make(chan struct{})
+		release   = make(chan struct{})
+		endpoints = []endpoint.Endpoint{
+			func(context.Context, interface{}) (interface{}, error) {
+				counts[0]++
+				close(started)
+				<-release
+				return struct{}{}, nil
+			},
+			func(context.Context, interface{}) (interface{}, error) { counts[1]++; return struct{}{}, nil },
+			func(context.Context, interface{}) (interface{}, error) { counts[2]++; return struct{}{}, nil },
+		}
+	)
+
+	balancer := NewLeastOutstanding(sd.FixedSubscriber(endpoints))
+
+	// Occupy the first endpoint.
+	e, err := balancer.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() { e(context.Background(), struct{}{}); close(done) }()
+	<-started
+
+	// Idle endpoints should get all other requests.
+	for i := 0; i < 10; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e(context.Background(), struct{}{})
+	}
+	close(release)
+	<-done
+
+	if want, have := 1, counts[0]; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := 10, counts[1]+counts[2]; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestLeastOutstandingNoEndpoints(t *testing.T) {
+	subscriber := sd.FixedSubscriber{}
+	balancer := NewLeastOutstanding(subscriber)
+	_, err := balancer.Endpoint()
+	if want, have := ErrNoEndpoints, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestLoadTrackerReset(t *testing.T) {
+	var (
+		endpoints  = []endpoint.Endpoint{endpoint.Nop, endpoint.Nop}
+		subscriber = &mutableSubscriber{endpoints: endpoints}
+		tracker    = newLoadTracker(subscriber)
+	)
+
+	loads, _ := tracker.current()
+	loads[0].endpoint(context.Background(), struct{}{})
+
+	// The same slice keeps its statistics.
+	again, _ := tracker.current()
+	if want, have := loads[0].load, again[0].load; want != have {
+		t.Errorf("want %p, have %p", want, have)
+	}
+
+	// A new slice of a subscriber without instance strings starts over.
+	subscriber.endpoints = []endpoint.Endpoint{endpoint.Nop, endpoint.Nop}
+	fresh, _ := tracker.current()
+	if fresh[0].load == loads[0].load {
+		t.Errorf("want new statistics, have old")
+	}
+}
+
+func TestLoadTrackerSurvivesRefresh(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		factory = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) {
+				<-release
+				return struct{}{}, nil
+			}, nil, nil
+		}
+		c       = cache.New(factory, log.NewNopLogger())
+		tracker = newLoadTracker(c)
+		done    = make(chan struct{})
+	)
+	c.Update([]string{"a", "b"})
+
+	loads, _ := tracker.current()
+	go func() {
+		loads[1].endpoint(context.Background(), struct{}{})
+		close(done)
+	}()
+	for loads[1].inFlight() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A refresh yields a new map, but an instance keeps its load, even when
+	// others come and go.
+	c.Update([]string{"a", "b"})
+	c.Update([]string{"b", "c"})
+	fresh, _ := tracker.current()
+	if want, have := int64(1), fresh[0].inFlight(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	// The request in flight across the refresh completes on the same load.
+	close(release)
+	<-done
+	if want, have := int64(0), fresh[0].inFlight(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := int64(0), fresh[1].inFlight(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+type mutableSubscriber struct {
+	endpoints []endpoint.Endpoint
+}
+
+func (s *mutableSubscriber) Endpoints() ([]endpoint.Endpoint, error) { return s.endpoints, nil }
@@ -0,0 +1,121 @@
+package lb
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/identity"
+)
+
+// decay is the weight given to the most recent latency sample in the moving
+// average of an endpoint's latency.
+const decay = 0.25
+
+// loadTracker wraps the endpoints yielded by a subscriber, so that the number
+// of requests in flight and the latency of each of them can be observed.
+//
+// Loads are kept per instance string, for as long as the subscriber yields
+// the instance, so the subscriber should be an InstanceSubscriber. The
+// endpoints of other subscribers are told apart by their position, and start
+// over whenever the subscriber yields a different slice.
+type loadTracker struct {
+	s sd.Subscriber
+
+	mtx    sync.Mutex
+	source interface{} // the instances of s the endpoints were wrapped for
+	loads  []trackedLoad
+	byKey  map[string]*load
+}
+
+// trackedLoad is the current endpoint of an instance, wrapped to update its
+// load.
+type trackedLoad struct {
+	endpoint endpoint.Endpoint
+	*load
+}
+
+func newLoadTracker(s sd.Subscriber) *loadTracker {
+	return &loadTracker{s: s}
+}
+
+// current returns the load of every endpoint currently yielded by the
+// subscriber, ordered like its endpoints.
+func (t *loadTracker) current() ([]trackedLoad, error) {
+	instances, err := instancesOf(t.s)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.loads == nil || !identity.Same(instances, t.source) {
+		keys, endpoints := keyed(instances)
+		if _, ok := instances.([]endpoint.Endpoint); ok {
+			t.byKey = nil // positions in a different slice, see keyed
+		}
+		byKey := make(map[string]*load, len(keys))
+		t.loads = make([]trackedLoad, len(keys))
+		for i, key := range keys {
+			l, ok := t.byKey[key]
+			if !ok {
+				l = &load{}
+			}
+			byKey[key] = l
+			t.loads[i] = trackedLoad{endpoint: l.wrap(endpoints[i]), load: l}
+		}
+		t.source, t.byKey = instances, byKey
+	}
+	if len(t.loads) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	return t.loads, nil
+}
+
+// load tracks the requests in flight to a single instance, and a moving
+// average of their latency.
+type load struct {
+	outstanding int64
+
+	mtx     sync.Mutex
+	latency float64 // nanoseconds; zero until the first request completes
+}
+
+func (l *load) wrap(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		atomic.AddInt64(&l.outstanding, 1)
+		defer func(begin time.Time) {
+			atomic.AddInt64(&l.outstanding, -1)
+			l.observe(time.Since(begin))
+		}(time.Now())
+		return next(ctx, request)
+	}
+}
+
+func (l *load) observe(d time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.latency == 0 {
+		l.latency = float64(d)
+		return
+	}
+	l.latency = decay*float64(d) + (1-decay)*l.latency
+}
+
+func (l *load) inFlight() int64 {
+	return atomic.LoadInt64(&l.outstanding)
+}
+
+// cost estimates the time a new request would take to complete: the average
+// latency, multiplied by the number of requests it would be queued behind.
+// Endpoints without a latency sample cost nothing, so they're tried first.
+func (l *load) cost() float64 {
+	l.mtx.Lock()
+	latency := l.latency
+	l.mtx.Unlock()
+	return latency * float64(l.inFlight()+1)
+}
@@ -0,0 +1,266 @@
+package lb
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/identity"
+)
+
+// OutlierDetector is a subscriber that watches the outcome of the requests
+// made to the endpoints of another subscriber, and temporarily ejects those
+// that misbehave. Wrap a subscriber with it, and pass it to any balancer.
+//
+// An endpoint is ejected after a number of consecutive errors, or, if
+// configured, when its error rate or average latency over an interval exceeds
+// a threshold. It stays ejected for the base ejection time, doubling with
+// every subsequent ejection, up to a maximum. No more than a fraction of the
+// endpoints are ejected at any time, so a failure of the whole pool doesn't
+// leave the balancer with nothing to choose from.
+//
+// Outcomes are only observed for requests made via the endpoints yielded by
+// the detector. Statistics and ejections are kept per instance string, for as
+// long as the wrapped subscriber yields the instance, so the subscriber
+// should be an InstanceSubscriber. The endpoints of other subscribers are
+// told apart by their position, and start over whenever the subscriber yields
+// a different slice.
+type OutlierDetector struct {
+	s                  sd.Subscriber
+	consecutiveErrors  int
+	errorRate          float64
+	minRequests        int
+	latency            time.Duration
+	interval           time.Duration
+	baseEjection       time.Duration
+	maxEjection        time.Duration
+	maxEjectedFraction float64
+	now                func() time.Time
+
+	mtx     sync.Mutex
+	source  interface{} // the instances of s the hosts were made for
+	hosts   []*host
+	byKey   map[string]*host
+	version uint64 // incremented whenever an endpoint is ejected or restored
+	cache   []endpoint.Endpoint
+	cacheAt uint64
+}
+
+type host struct {
+	endpoint     endpoint.Endpoint // wraps the current one of the instance
+	consecutive  int
+	requests     int
+	errors       int
+	latency      time.Duration // sum over the current interval
+	windowStart  time.Time
+	ejections    int
+	ejectedUntil time.Time // zero if not ejected
+	restoredAt   time.Time
+}
+
+var _ sd.Subscriber = &OutlierDetector{}
+
+// NewOutlierDetector returns an OutlierDetector yielding the healthy
+// endpoints of s.
+func NewOutlierDetector(s sd.Subscriber, options ...OutlierOption) *OutlierDetector {
+	d := &OutlierDetector{
+		s:                  s,
+		consecutiveErrors:  5,
+		interval:           10 * time.Second,
+		baseEjection:       30 * time.Second,
+		maxEjection:        5 * time.Minute,
+		maxEjectedFraction: 0.5,
+		now:                time.Now,
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// OutlierOption sets an optional parameter for the OutlierDetector.
+type OutlierOption func(*OutlierDetector)
+
+// OutlierConsecutiveErrors sets the number of consecutive errors after which
+// an endpoint is ejected. Zero disables the check. The default is 5.
+func OutlierConsecutiveErrors(n int) OutlierOption {
+	return func(d *OutlierDetector) { d.consecutiveErrors = n }
+}
+
+// OutlierErrorRate ejects endpoints whose fraction of failed requests over an
+// interval is at least rate, provided they served at least minRequests in that
+// interval. By default, the error rate isn't checked.
+func OutlierErrorRate(rate float64, minRequests int) OutlierOption {
+	return func(d *OutlierDetector) { d.errorRate, d.minRequests = rate, minRequests }
+}
+
+// OutlierLatency ejects endpoints whose average latency over an interval is
+// at least the threshold. The minimum number of requests set with
+// OutlierErrorRate applies. By default, latency isn't checked.
+func OutlierLatency(threshold time.Duration) OutlierOption {
+	return func(d *OutlierDetector) { d.latency = threshold }
+}
+
+// OutlierInterval sets the interval over which error rates and latencies are
+// measured. The default is 10 seconds.
+func OutlierInterval(interval time.Duration) OutlierOption {
+	return func(d *OutlierDetector) { d.interval = interval }
+}
+
+// OutlierEjectionTime sets the time an endpoint is ejected for the first
+// time, and the maximum time it's ejected for after repeated ejections. An
+// endpoint that behaves for max after being restored starts over at base.
+// The defaults are 30 seconds and 5 minutes.
+func OutlierEjectionTime(base, max time.Duration) OutlierOption {
+	return func(d *OutlierDetector) { d.baseEjection, d.maxEjection = base, max }
+}
+
+// OutlierMaxEjectedFraction sets the maximum fraction of endpoints that may be
+// ejected at the same time. The default is 0.5.
+func OutlierMaxEjectedFraction(fraction float64) OutlierOption {
+	return func(d *OutlierDetector) { d.maxEjectedFraction = fraction }
+}
+
+// Endpoints implements the Subscriber interface. As long as no endpoint is
+// ejected or restored, the same slice is returned.
+func (d *OutlierDetector) Endpoints() ([]endpoint.Endpoint, error) {
+	instances, err := instancesOf(d.s)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.hosts == nil || !identity.Same(instances, d.source) {
+		d.update(instances)
+	}
+
+	now := d.now()
+	for _, h := range d.hosts {
+		if !h.ejectedUntil.IsZero() && !now.Before(h.ejectedUntil) {
+			d.restore(h, now)
+		}
+	}
+
+	if d.cache == nil || d.cacheAt != d.version {
+		d.cache = make([]endpoint.Endpoint, 0, len(d.hosts))
+		for _, h := range d.hosts {
+			if h.ejectedUntil.IsZero() {
+				d.cache = append(d.cache, h.endpoint)
+			}
+		}
+		d.cacheAt = d.version
+	}
+	return d.cache, nil
+}
+
+// update makes hosts for new instances, and wraps the current endpoints of
+// the others, keeping their statistics. It must be called with the mutex
+// held.
+func (d *OutlierDetector) update(instances interface{}) {
+	keys, endpoints := keyed(instances)
+	if _, ok := instances.([]endpoint.Endpoint); ok {
+		d.byKey = nil // positions in a different slice, see keyed
+	}
+
+	byKey := make(map[string]*host, len(keys))
+	d.hosts = make([]*host, len(keys))
+	for i, key := range keys {
+		h, ok := d.byKey[key]
+		if !ok {
+			h = &host{windowStart: d.now()}
+		}
+		h.endpoint = d.wrap(h, endpoints[i])
+		byKey[key] = h
+		d.hosts[i] = h
+	}
+	d.source, d.byKey, d.cache = instances, byKey, nil
+}
+
+func (d *OutlierDetector) wrap(h *host, next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		begin := d.now()
+		response, err := next(ctx, request)
+		d.observe(h, err, d.now().Sub(begin))
+		return response, err
+	}
+}
+
+func (d *OutlierDetector) observe(h *host, err error, latency time.Duration) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if !h.ejectedUntil.IsZero() {
+		return // requests that were in flight when it was ejected
+	}
+
+	h.requests++
+	h.latency += latency
+	if err != nil {
+		h.errors++
+		h.consecutive++
+	} else {
+		h.consecutive = 0
+	}
+
+	now := d.now()
+	if d.consecutiveErrors > 0 && h.consecutive >= d.consecutiveErrors {
+		d.eject(h, now)
+		return
+	}
+	if now.Sub(h.windowStart) < d.interval {
+		return
+	}
+	if h.requests >= d.minRequests {
+		var (
+			rate    = float64(h.errors) / float64(h.requests)
+			average = h.latency / time.Duration(h.requests)
+		)
+		if (d.errorRate > 0 && rate >= d.errorRate) || (d.latency > 0 && average >= d.latency) {
+			d.eject(h, now)
+			return
+		}
+	}
+	h.requests, h.errors, h.latency, h.windowStart = 0, 0, 0, now
+}
+
+// eject must be called with the mutex held.
+func (d *OutlierDetector) eject(h *host, now time.Time) {
+	h.consecutive, h.requests, h.errors, h.latency, h.windowStart = 0, 0, 0, 0, now
+
+	var ejected int
+	for _, other := range d.hosts {
+		if !other.ejectedUntil.IsZero() {
+			ejected++
+		}
+	}
+	if float64(ejected+1) > d.maxEjectedFraction*float64(len(d.hosts)) {
+		return // too many ejected already
+	}
+
+	if !h.restoredAt.IsZero() && now.Sub(h.restoredAt) >= d.maxEjection {
+		h.ejections = 0 // it behaved long enough to be forgiven
+	}
+	duration := d.baseEjection
+	for i := 0; i < h.ejections && duration < d.maxEjection; i++ {
+		duration *= 2
+	}
+	if duration > d.maxEjection {
+		duration = d.maxEjection
+	}
+	h.ejections++
+	h.ejectedUntil = now.Add(duration)
+	d.version++
+}
+
+// restore must be called with the mutex held.
+func (d *OutlierDetector) restore(h *host, now time.Time) {
+	h.ejectedUntil = time.Time{}
+	h.restoredAt = now
+	h.windowStart = now
+	d.version++
+}
@@ -0,0 +1,176 @@
+package lb
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/cache"
+)
+
+func TestOutlierConsecutiveErrors(t *testing.T) {
+	var (
+		clock     = &fakeClock{t: time.Unix(0, 0)}
+		failing   = func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("fail") }
+		endpoints = sd.FixedSubscriber{failing, endpoint.Nop, endpoint.Nop}
+		d         = NewOutlierDetector(endpoints, OutlierConsecutiveErrors(2), OutlierEjectionTime(time.Second, 3*time.Second))
+	)
+	d.now = clock.Now
+
+	invokeN(t, d, 0, 2)
+	assertEndpoints(t, d, 2)
+
+	// Restored after the base ejection time.
+	clock.Add(time.Second)
+	assertEndpoints(t, d, 3)
+
+	// Ejected for twice as long the second time.
+	invokeN(t, d, 0, 2)
+	clock.Add(time.Second)
+	assertEndpoints(t, d, 2)
+	clock.Add(time.Second)
+	assertEndpoints(t, d, 3)
+}
+
+func TestOutlierMaxEjectedFraction(t *testing.T) {
+	var (
+		failing   = func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("fail") }
+		endpoints = sd.FixedSubscriber{failing, failing, failing, endpoint.Nop}
+		d         = NewOutlierDetector(endpoints, OutlierConsecutiveErrors(1), OutlierMaxEjectedFraction(0.5))
+	)
+	for i := 0; i < 3; i++ {
+		all, _ := d.Endpoints()
+		for _, e := range all {
+			e(context.Background(), struct{}{})
+		}
+	}
+	assertEndpoints(t, d, 2)
+}
+
+func TestOutlierErrorRateAndLatency(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var (
+		flaky = func(_ context.Context, request interface{}) (interface{}, error) {
+			if request.(int)%2 == 0 {
+				return nil, errors.New("fail")
+			}
+			return struct{}{}, nil
+		}
+		slow = func(context.Context, interface{}) (interface{}, error) {
+			clock.Add(100 * time.Millisecond)
+			return struct{}{}, nil
+		}
+		endpoints = sd.FixedSubscriber{flaky, slow, endpoint.Nop, endpoint.Nop}
+		d         = NewOutlierDetector(endpoints,
+			OutlierConsecutiveErrors(0),
+			OutlierErrorRate(0.5, 4),
+			OutlierLatency(50*time.Millisecond),
+			OutlierInterval(time.Second),
+		)
+	)
+	d.now = clock.Now
+
+	all, _ := d.Endpoints()
+	for i := 0; i < 4; i++ {
+		all[0](context.Background(), i)
+		all[1](context.Background(), i)
+	}
+	assertEndpoints(t, d, 4) // the interval hasn't elapsed yet
+
+	clock.Add(time.Second)
+	all[0](context.Background(), 0) // 3 of 5 requests failed
+	all[1](context.Background(), 0)
+	assertEndpoints(t, d, 2)
+}
+
+func TestOutlierSurvivesRefresh(t *testing.T) {
+	var (
+		clock   = &fakeClock{t: time.Unix(0, 0)}
+		failing = func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("fail") }
+		factory = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			if instance == "a" {
+				return failing, nil, nil
+			}
+			return endpoint.Nop, nil, nil
+		}
+		c = cache.New(factory, log.NewNopLogger())
+		d = NewOutlierDetector(c, OutlierConsecutiveErrors(1), OutlierEjectionTime(time.Second, 3*time.Second))
+	)
+	d.now = clock.Now
+	c.Update([]string{"a", "b", "c", "d"})
+
+	invokeN(t, d, 0, 1)
+	assertEndpoints(t, d, 3)
+
+	// A refresh with the same instances yields a new map, but the instance
+	// stays ejected, and so does one that survives a change.
+	c.Update([]string{"a", "b", "c", "d"})
+	assertEndpoints(t, d, 3)
+	c.Update([]string{"a", "b", "c", "d", "e"})
+	assertEndpoints(t, d, 4)
+
+	// The growing back-off isn't reset by refreshes either.
+	clock.Add(time.Second)
+	assertEndpoints(t, d, 5)
+	invokeN(t, d, 0, 1)
+	c.Update([]string{"a", "b", "c", "d", "e"})
+	clock.Add(time.Second)
+	assertEndpoints(t, d, 4)
+	clock.Add(time.Second)
+	assertEndpoints(t, d, 5)
+}
+
+func TestOutlierFixedInstances(t *testing.T) {
+	var (
+		failing = func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("fail") }
+		s       = sd.FixedInstances(map[string]endpoint.Endpoint{"a": failing, "b": endpoint.Nop, "c": endpoint.Nop})
+		d       = NewOutlierDetector(s, OutlierConsecutiveErrors(2))
+	)
+	invokeN(t, d, 0, 1)
+	invokeN(t, d, 0, 1)
+	assertEndpoints(t, d, 2)
+}
+
+func invokeN(t *testing.T, d *OutlierDetector, i, n int) {
+	all, err := d.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < n; j++ {
+		all[i](context.Background(), struct{}{})
+	}
+}
+
+func assertEndpoints(t *testing.T, s sd.Subscriber, want int) {
+	endpoints, err := s.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := len(endpoints); want != have {
+		t.Errorf("want %d endpoints, have %d", want, have)
+	}
+}
+
+type fakeClock struct {
+	mtx sync.Mutex
+	t   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = c.t.Add(d)
+}
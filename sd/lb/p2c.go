@@ -0,0 +1,58 @@
+package lb
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// NewPowerOfTwoChoices returns a load balancer that picks two endpoints at
+// random, and selects the one with the lower expected cost: the average
+// latency of the endpoint, multiplied by the number of requests in flight.
+// Compared to NewLeastOutstanding, it avoids herding many clients onto the
+// same endpoint, and it steers traffic away from slow endpoints even when
+// they're not heavily loaded.
+//
+// Requests are only tracked if they're made via the endpoints returned by the
+// balancer, so every caller should use the same balancer.
+func NewPowerOfTwoChoices(s sd.Subscriber, seed int64) Balancer {
+	return &powerOfTwoChoices{
+		t: newLoadTracker(s),
+		r: rand.New(rand.NewSource(seed)),
+	}
+}
+
+type powerOfTwoChoices struct {
+	t *loadTracker
+
+	mtx sync.Mutex
+	r   *rand.Rand
+}
+
+func (p *powerOfTwoChoices) Endpoint() (endpoint.Endpoint, error) {
+	loads, err := p.t.current()
+	if err != nil {
+		return nil, err
+	}
+	if len(loads) == 1 {
+		return loads[0].endpoint, nil
+	}
+
+	p.mtx.Lock()
+	i := p.r.Intn(len(loads))
+	j := p.r.Intn(len(loads) - 1)
+	p.mtx.Unlock()
+	if j >= i {
+		j++ // distinct from i
+	}
+
+	// Before any latency is known, costs are zero; fall back to the number of
+	// requests in flight.
+	a, b := loads[i], loads[j]
+	if ca, cb := a.cost(), b.cost(); cb < ca || (cb == ca && b.inFlight() < a.inFlight()) {
+		return b.endpoint, nil
+	}
+	return a.endpoint, nil
+}
@@ -0,0 +1,89 @@
+package lb
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestPowerOfTwoChoicesAvoidsSlowEndpoint(t *testing.T) {
+	var (
+		n          = 3
+		counts     = make([]int, n)
+		endpoints  = make([]endpoint.Endpoint, n)
+		iterations = 300
+	)
+	for i := 0; i < n; i++ {
+		i0 := i
+		endpoints[i] = func(context.Context, interface{}) (interface{}, error) {
+			counts[i0]++
+			if i0 == 0 {
+				time.Sleep(10 * time.Millisecond)
+			}
+			return struct{}{}, nil
+		}
+	}
+
+	balancer := NewPowerOfTwoChoices(sd.FixedSubscriber(endpoints), 12345)
+	for i := 0; i < iterations; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e(context.Background(), struct{}{})
+	}
+
+	// Once its latency is known, the slow endpoint loses every comparison.
+	if max, have := 5, counts[0]; have > max {
+		t.Errorf("slow endpoint got %d requests, want at most %d (counts %v)", have, max, counts)
+	}
+}
+
+func TestPowerOfTwoChoicesNoEndpoints(t *testing.T) {
+	subscriber := sd.FixedSubscriber{}
+	balancer := NewPowerOfTwoChoices(subscriber, 1415926)
+	_, err := balancer.Endpoint()
+	if want, have := ErrNoEndpoints, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestPowerOfTwoChoicesNoRace(t *testing.T) {
+	balancer := NewPowerOfTwoChoices(sd.FixedSubscriber([]endpoint.Endpoint{
+		endpoint.Nop,
+		endpoint.Nop,
+		endpoint.Nop,
+	}), 1)
+
+	var (
+		n     = 100
+		wg    sync.WaitGroup
+		count uint64
+	)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				e, err := balancer.Endpoint()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				e(context.Background(), struct{}{})
+				atomic.AddUint64(&count, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if want, have := uint64(n*100), atomic.LoadUint64(&count); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
@@ -1,8 +1,12 @@
 package lb
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/context"
@@ -10,48 +14,228 @@ import (
 	"github.com/go-kit/kit/endpoint"
 )
 
+// RetryError is returned by the endpoints produced by Retry and
+// RetryWithCallback when a request ultimately fails. It keeps every error
+// returned by an attempt, so they may be inspected by the caller.
+type RetryError struct {
+	RawErrors []error // errors returned by each attempt, in order
+	Final     error   // the terminating error
+}
+
+// Error implements the error interface.
+func (e RetryError) Error() string {
+	var suffix string
+	if len(e.RawErrors) > 1 {
+		a := make([]string, len(e.RawErrors)-1)
+		for i := 0; i < len(e.RawErrors)-1; i++ { // last one is Final
+			a[i] = e.RawErrors[i].Error()
+		}
+		suffix = fmt.Sprintf(" (previously: %s)", strings.Join(a, "; "))
+	}
+	return fmt.Sprintf("%v%s", e.Final, suffix)
+}
+
+// Callback is invoked with the error returned by every failed attempt, and
+// the number of attempts made so far, starting at 1. It decides whether to
+// keep trying, and may return a replacement for the error; if not, the
+// received error becomes the final one. Callbacks can be used to stop
+// retrying errors that aren't transient, like validation or business errors.
+type Callback func(n int, received error) (keepTrying bool, replacement error)
+
 // Retry wraps a service load balancer and returns an endpoint oriented load
 // balancer for the specified service method.
 // Requests to the endpoint will be automatically load balanced via the load
 // balancer. Requests that return errors will be retried until they succeed,
 // up to max times, or until the timeout is elapsed, whichever comes first.
-func Retry(max int, timeout time.Duration, b Balancer) endpoint.Endpoint {
+//
+// If the timeout elapses, or the context passed to the endpoint is done, the
+// error of the context is returned, e.g. context.DeadlineExceeded. If the
+// request fails otherwise, the returned error is a RetryError.
+func Retry(max int, timeout time.Duration, b Balancer, options ...RetryOption) endpoint.Endpoint {
+	if b == nil {
+		panic("nil Balancer")
+	}
+	if max <= 0 {
+		return func(context.Context, interface{}) (interface{}, error) {
+			return nil, RetryError{Final: errNoAttempts}
+		}
+	}
+	return newRetry(timeout, b, maxRetries(max), false, options...)
+}
+
+var errNoAttempts = errors.New("retry attempts exceeded")
+
+func maxRetries(max int) Callback {
+	return func(n int, err error) (keepTrying bool, replacement error) {
+		return n < max, nil
+	}
+}
+
+// RetryWithCallback wraps a service load balancer and returns an endpoint
+// oriented load balancer for the specified service method. Requests that
+// return errors will be retried for as long as the callback returns true, or
+// until the timeout is elapsed, whichever comes first. A request is never
+// retried once the context passed to the endpoint is done.
+//
+// If the request fails, the returned error is a RetryError. If the timeout
+// elapsed, or the context passed to the endpoint is done, its Final error is
+// that of the context, and the error of the interrupted attempt is the last
+// of its RawErrors.
+func RetryWithCallback(timeout time.Duration, b Balancer, cb Callback, options ...RetryOption) endpoint.Endpoint {
 	if b == nil {
 		panic("nil Balancer")
 	}
-	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+	if cb == nil {
+		panic("nil Callback")
+	}
+	return newRetry(timeout, b, cb, true, options...)
+}
+
+// newRetry returns the endpoint of Retry and RetryWithCallback. Unless
+// wrapContextErr is set, errors of the context are returned as they are,
+// as Retry always did.
+func newRetry(timeout time.Duration, b Balancer, cb Callback, wrapContextErr bool, options ...RetryOption) endpoint.Endpoint {
+	r := &retrier{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		var (
 			newctx, cancel = context.WithTimeout(ctx, timeout)
-			responses      = make(chan interface{}, 1)
-			errs           = make(chan error, 1)
-			a              = []string{}
+			final          RetryError
 		)
 		defer cancel()
-		for i := 1; i <= max; i++ {
-			go func() {
-				e, err := b.Endpoint()
-				if err != nil {
-					errs <- err
-					return
-				}
-				response, err := e(newctx, request)
-				if err != nil {
-					errs <- err
-					return
-				}
-				responses <- response
-			}()
-
-			select {
-			case <-newctx.Done():
-				return nil, newctx.Err()
-			case response := <-responses:
+		done := func() error {
+			if !wrapContextErr {
+				return newctx.Err()
+			}
+			final.Final = newctx.Err()
+			return final
+		}
+		for i := 1; ; i++ {
+			response, err := r.attempt(newctx, b, request)
+			if err == nil {
 				return response, nil
-			case err := <-errs:
-				a = append(a, err.Error())
-				continue
 			}
+			final.RawErrors = append(final.RawErrors, err)
+			if newctx.Err() != nil {
+				return nil, done()
+			}
+
+			keepTrying, replacement := cb(i, err)
+			if replacement != nil {
+				err = replacement
+			}
+			if !keepTrying {
+				final.Final = err
+				return nil, final
+			}
+
+			if d := r.backoff(i); d > 0 {
+				select {
+				case <-newctx.Done():
+					return nil, done()
+				case <-time.After(d):
+				}
+			}
+		}
+	}
+}
+
+// RetryOption sets an optional parameter for Retry and RetryWithCallback.
+type RetryOption func(*retrier)
+
+// RetryBackoff makes the endpoint wait between attempts. The first retry
+// waits initial; each subsequent one waits twice as long as the previous one,
+// up to max, if max is positive. By default, retries are made immediately.
+func RetryBackoff(initial, max time.Duration) RetryOption {
+	return func(r *retrier) { r.initial, r.max = initial, max }
+}
+
+// RetryJitter randomly shortens each backoff by up to the given fraction of
+// its length, so that clients that failed at the same time don't all retry at
+// the same time, too. A fraction of 1 waits anywhere between zero and the
+// full backoff. The default is 0, i.e. no jitter.
+func RetryJitter(fraction float64) RetryOption {
+	return func(r *retrier) { r.jitter = fraction }
+}
+
+// RetryAttemptTimeout limits the duration of each attempt, so that a single
+// slow endpoint doesn't consume the whole timeout. The attempt fails with
+// context.DeadlineExceeded, and may be retried. By default, attempts are only
+// limited by the overall timeout.
+func RetryAttemptTimeout(timeout time.Duration) RetryOption {
+	return func(r *retrier) { r.attemptTimeout = timeout }
+}
+
+type retrier struct {
+	initial        time.Duration
+	max            time.Duration
+	jitter         float64
+	attemptTimeout time.Duration
+
+	mtx  sync.Mutex
+	rand *rand.Rand
+}
+
+// attempt makes a single attempt. It returns when the attempt completes, or
+// its context is done, whichever comes first; endpoints that ignore their
+// context are left to finish in the background.
+func (r *retrier) attempt(ctx context.Context, b Balancer, request interface{}) (interface{}, error) {
+	cancel := context.CancelFunc(func() {})
+	if r.attemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.attemptTimeout)
+	}
+	defer cancel()
+
+	var (
+		responses = make(chan interface{}, 1)
+		errs      = make(chan error, 1)
+	)
+	go func() {
+		e, err := b.Endpoint()
+		if err != nil {
+			errs <- err
+			return
+		}
+		response, err := e(ctx, request)
+		if err != nil {
+			errs <- err
+			return
 		}
-		return nil, fmt.Errorf("retry attempts exceeded (%s)", strings.Join(a, "; "))
+		responses <- response
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case response := <-responses:
+		return response, nil
+	case err := <-errs:
+		return nil, err
+	}
+}
+
+// backoff returns the time to wait after the nth failed attempt.
+func (r *retrier) backoff(n int) time.Duration {
+	if r.initial <= 0 {
+		return 0
+	}
+	d := r.initial
+	for i := 1; i < n && (r.max <= 0 || d < r.max) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if r.max > 0 && d > r.max {
+		d = r.max
+	}
+	if r.jitter > 0 {
+		r.mtx.Lock()
+		f := r.rand.Float64()
+		r.mtx.Unlock()
+		d -= time.Duration(r.jitter * f * float64(d))
 	}
+	return d
 }
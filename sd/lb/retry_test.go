@@ -2,6 +2,7 @@ package lb_test
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -88,3 +89,137 @@ func TestRetryTimeout(t *testing.T) {
 		t.Errorf("wanted %v, got none", context.DeadlineExceeded)
 	}
 }
+
+func TestRetryNoAttempts(t *testing.T) {
+	var (
+		calls = 0
+		e     = func(context.Context, interface{}) (interface{}, error) { calls++; return struct{}{}, nil }
+		retry = loadbalancer.Retry(0, time.Second, loadbalancer.NewRoundRobin(sd.FixedSubscriber{0: e}))
+	)
+	if _, err := retry(context.Background(), struct{}{}); err == nil {
+		t.Error("expected error, got none")
+	}
+	if want, have := 0, calls; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestRetryWithCallbackTimeout(t *testing.T) {
+	var (
+		errSlow = errors.New("slow")
+		e       = func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return nil, errSlow
+		}
+		cb    = func(int, error) (bool, error) { return true, nil }
+		retry = loadbalancer.RetryWithCallback(10*time.Millisecond, loadbalancer.NewRoundRobin(sd.FixedSubscriber{0: e}), cb)
+	)
+	_, err := retry(context.Background(), struct{}{})
+	retryErr, ok := err.(loadbalancer.RetryError)
+	if !ok {
+		t.Fatalf("want RetryError, have %T", err)
+	}
+	if want, have := context.DeadlineExceeded, retryErr.Final; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 1, len(retryErr.RawErrors); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if raw := retryErr.RawErrors[0]; raw != errSlow && raw != context.DeadlineExceeded {
+		t.Errorf("want the error of the interrupted attempt, have %v", raw)
+	}
+}
+
+func TestRetryErrorKeepsRawErrors(t *testing.T) {
+	var (
+		errOne     = errors.New("error one")
+		errTwo     = errors.New("error two")
+		subscriber = sd.FixedSubscriber{
+			0: func(context.Context, interface{}) (interface{}, error) { return nil, errOne },
+			1: func(context.Context, interface{}) (interface{}, error) { return nil, errTwo },
+		}
+		retry = loadbalancer.Retry(2, time.Second, loadbalancer.NewRoundRobin(subscriber))
+	)
+	_, err := retry(context.Background(), struct{}{})
+	retryErr, ok := err.(loadbalancer.RetryError)
+	if !ok {
+		t.Fatalf("want RetryError, have %T", err)
+	}
+	if want, have := []error{errOne, errTwo}, retryErr.RawErrors; len(want) != len(have) || want[0] != have[0] || want[1] != have[1] {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := errTwo, retryErr.Final; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "error two (previously: error one)", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestRetryWithCallback(t *testing.T) {
+	var (
+		errBusiness = errors.New("not found")
+		errReplaced = errors.New("replaced")
+		calls       = 0
+		e           = func(context.Context, interface{}) (interface{}, error) { calls++; return nil, errBusiness }
+		cb          = func(n int, err error) (bool, error) {
+			if err == errBusiness {
+				return false, errReplaced // don't retry business errors
+			}
+			return n < 10, nil
+		}
+		retry = loadbalancer.RetryWithCallback(time.Second, loadbalancer.NewRoundRobin(sd.FixedSubscriber{0: e}), cb)
+	)
+	_, err := retry(context.Background(), struct{}{})
+	if want, have := 1, calls; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := errReplaced, err.(loadbalancer.RetryError).Final; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var (
+		times []time.Time
+		e     = func(context.Context, interface{}) (interface{}, error) {
+			times = append(times, time.Now())
+			return nil, errors.New("fail")
+		}
+		retry = loadbalancer.Retry(4, time.Second, loadbalancer.NewRoundRobin(sd.FixedSubscriber{0: e}),
+			loadbalancer.RetryBackoff(10*time.Millisecond, 20*time.Millisecond),
+			loadbalancer.RetryJitter(0.5),
+		)
+	)
+	if _, err := retry(context.Background(), struct{}{}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+	if want, have := 4, len(times); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	// Backoffs are 10ms, 20ms and 20ms, less up to half of each for jitter.
+	for i, min := range []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond} {
+		if have := times[i+1].Sub(times[i]); have < min {
+			t.Errorf("attempt %d: want at least %v between attempts, have %v", i+2, min, have)
+		}
+	}
+}
+
+func TestRetryAttemptTimeout(t *testing.T) {
+	var (
+		calls uint64
+		e     = func(ctx context.Context, _ interface{}) (interface{}, error) {
+			if atomic.AddUint64(&calls, 1) == 1 {
+				<-ctx.Done() // first attempt hangs until it times out
+				return nil, ctx.Err()
+			}
+			return struct{}{}, nil
+		}
+		retry = loadbalancer.Retry(2, time.Second, loadbalancer.NewRoundRobin(sd.FixedSubscriber{0: e}),
+			loadbalancer.RetryAttemptTimeout(10*time.Millisecond),
+		)
+	)
+	if _, err := retry(context.Background(), struct{}{}); err != nil {
+		t.Error(err)
+	}
+}
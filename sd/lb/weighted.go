@@ -0,0 +1,100 @@
+package lb
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/identity"
+)
+
+// NewWeightedRoundRobin returns a load balancer that yields endpoints in
+// proportion to the weights of their instances, as given by the subscriber's
+// metadata. Instances without a weight count as weight 1.
+//
+// The sequence is smooth: rather than yielding an instance of weight 5 five
+// times in a row, it interleaves instances as evenly as their weights allow.
+// That's the algorithm used by nginx.
+func NewWeightedRoundRobin(s sd.MetadataSubscriber) Balancer {
+	return &weightedRoundRobin{s: s}
+}
+
+type weightedRoundRobin struct {
+	s sd.MetadataSubscriber
+
+	mtx       sync.Mutex
+	instances map[string]endpoint.Endpoint // the peers were built from
+	metadata  map[string]sd.Instance       // the weights were taken from
+	peers     []*weightedPeer
+}
+
+type weightedPeer struct {
+	name    string
+	e       endpoint.Endpoint
+	weight  int
+	current int
+}
+
+func (w *weightedRoundRobin) Endpoint() (endpoint.Endpoint, error) {
+	instances, err := w.s.Instances()
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	metadata, err := w.s.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.update(instances, metadata)
+
+	var (
+		best  *weightedPeer
+		total int
+	)
+	for _, p := range w.peers {
+		p.current += p.weight
+		total += p.weight
+		if best == nil || p.current > best.current {
+			best = p
+		}
+	}
+	best.current -= total
+	return best.e, nil
+}
+
+// update rebuilds the peers if the instances or their metadata changed. The
+// position of surviving instances in the sequence is kept. It must be called
+// with the mutex held.
+func (w *weightedRoundRobin) update(instances map[string]endpoint.Endpoint, metadata map[string]sd.Instance) {
+	if w.peers != nil && identity.Same(instances, w.instances) && identity.Same(metadata, w.metadata) {
+		return
+	}
+
+	current := make(map[string]int, len(w.peers))
+	for _, p := range w.peers {
+		current[p.name] = p.current
+	}
+
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.peers = make([]*weightedPeer, len(names))
+	for i, name := range names {
+		w.peers[i] = &weightedPeer{
+			name:    name,
+			e:       instances[name],
+			weight:  metadata[name].EffectiveWeight(),
+			current: current[name],
+		}
+	}
+	w.instances, w.metadata = instances, metadata
+}
@@ -0,0 +1,86 @@
+package lb
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestWeightedRoundRobin(t *testing.T) {
+	s := &metadataSubscriber{
+		instances: map[string]endpoint.Endpoint{"a": named("a"), "b": named("b"), "c": named("c")},
+		metadata: map[string]sd.Instance{
+			"a": {Addr: "a", Weight: 5},
+			"b": {Addr: "b"}, // defaults to 1
+			"c": {Addr: "c", Weight: 1},
+		},
+	}
+	b := NewWeightedRoundRobin(s)
+
+	// The classic nginx example: smooth, not aaaaabc.
+	var sequence string
+	for i := 0; i < 7; i++ {
+		sequence += invokeBalancer(t, b)
+	}
+	if want, have := "aabacaa", sequence; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 700; i++ {
+		counts[invokeBalancer(t, b)]++
+	}
+	for name, want := range map[string]int{"a": 500, "b": 100, "c": 100} {
+		if have := counts[name]; want != have {
+			t.Errorf("%s: want %d, have %d", name, want, have)
+		}
+	}
+
+	// New weights take effect when the metadata changes.
+	s.metadata = map[string]sd.Instance{"a": {Addr: "a"}, "b": {Addr: "b", Weight: 2}, "c": {Addr: "c"}}
+	counts = map[string]int{}
+	for i := 0; i < 400; i++ {
+		counts[invokeBalancer(t, b)]++
+	}
+	for name, want := range map[string]int{"a": 100, "b": 200, "c": 100} {
+		if have := counts[name]; want != have {
+			t.Errorf("%s: want %d, have %d", name, want, have)
+		}
+	}
+}
+
+func TestWeightedRoundRobinNoEndpoints(t *testing.T) {
+	b := NewWeightedRoundRobin(&metadataSubscriber{})
+	if _, err := b.Endpoint(); err != ErrNoEndpoints {
+		t.Errorf("want %v, have %v", ErrNoEndpoints, err)
+	}
+}
+
+func invokeBalancer(t *testing.T, b Balancer) string {
+	e, err := b.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, _ := e(context.Background(), struct{}{})
+	return response.(string)
+}
+
+type metadataSubscriber struct {
+	instances map[string]endpoint.Endpoint
+	metadata  map[string]sd.Instance
+}
+
+func (s *metadataSubscriber) Endpoints() ([]endpoint.Endpoint, error) {
+	return sd.FixedInstances(s.instances).Endpoints()
+}
+
+func (s *metadataSubscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.instances, nil
+}
+
+func (s *metadataSubscriber) Metadata() (map[string]sd.Instance, error) {
+	return s.metadata, nil
+}
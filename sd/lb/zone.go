@@ -0,0 +1,152 @@
+package lb
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/identity"
+)
+
+// ZoneAware wraps a subscriber and restricts it to the instances in the local
+// zone, as given by the subscriber's metadata, so that requests don't leave
+// the zone unless they must. Stack a balancer on top of it to pick among
+// them, e.g.
+//
+//	lb.NewWeightedRoundRobin(lb.NewZoneAware(s, "us-east-1a", 0.5))
+//
+// The local zone spills over into the others when too few of its instances
+// are healthy. The healthy fraction is the number of local instances the
+// subscriber yields, divided by the number it describes in its metadata.
+// Subscribers that withhold unhealthy instances, like the one in package
+// sd/healthcheck, still describe them, so that the fraction drops as
+// instances fail. When it drops below the threshold, just enough instances of
+// other zones, in lexicographic order, are added to the local ones to make up
+// the shortfall, so that a single failure doesn't send most requests across
+// zones. When there are no local instances at all, every instance is yielded.
+type ZoneAware struct {
+	s         sd.MetadataSubscriber
+	zone      string
+	threshold float64
+
+	mtx       sync.Mutex
+	source    map[string]endpoint.Endpoint // instances of s the endpoints were chosen from
+	metadata  map[string]sd.Instance       // of s, as of the choice
+	endpoints []endpoint.Endpoint
+	instances map[string]endpoint.Endpoint
+}
+
+var (
+	_ sd.MetadataSubscriber = &ZoneAware{}
+	_ sd.StatusSubscriber   = &ZoneAware{}
+)
+
+// NewZoneAware returns a ZoneAware subscriber preferring instances in zone.
+// The threshold is the fraction of local instances, between 0 and 1, that
+// must be healthy for the local zone to be used exclusively.
+func NewZoneAware(s sd.MetadataSubscriber, zone string, threshold float64) *ZoneAware {
+	return &ZoneAware{
+		s:         s,
+		zone:      zone,
+		threshold: threshold,
+	}
+}
+
+// Endpoints implements the Subscriber interface. Endpoints are ordered
+// lexicographically by the corresponding instance string, and the same slice
+// is returned as long as neither the instances nor their metadata change.
+func (z *ZoneAware) Endpoints() ([]endpoint.Endpoint, error) {
+	if err := z.update(); err != nil {
+		return nil, err
+	}
+	z.mtx.Lock()
+	defer z.mtx.Unlock()
+	return z.endpoints, nil
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (z *ZoneAware) Instances() (map[string]endpoint.Endpoint, error) {
+	if err := z.update(); err != nil {
+		return nil, err
+	}
+	z.mtx.Lock()
+	defer z.mtx.Unlock()
+	return z.instances, nil
+}
+
+// Metadata implements the MetadataSubscriber interface. It passes on the
+// metadata of the wrapped subscriber.
+func (z *ZoneAware) Metadata() (map[string]sd.Instance, error) {
+	return z.s.Metadata()
+}
+
+// Status implements the StatusSubscriber interface. It passes on the status
+// of the wrapped subscriber, if it reports any.
+func (z *ZoneAware) Status() sd.Status {
+	if ss, ok := z.s.(sd.StatusSubscriber); ok {
+		return ss.Status()
+	}
+	return sd.Status{}
+}
+
+func (z *ZoneAware) update() error {
+	instances, err := z.s.Instances()
+	if err != nil {
+		return err
+	}
+	metadata, err := z.s.Metadata()
+	if err != nil {
+		return err
+	}
+
+	z.mtx.Lock()
+	defer z.mtx.Unlock()
+	if z.instances != nil && identity.Same(instances, z.source) && identity.Same(metadata, z.metadata) {
+		return nil
+	}
+
+	var local []string
+	for name := range instances {
+		if metadata[name].Zone == z.zone {
+			local = append(local, name)
+		}
+	}
+	var known int
+	for _, instance := range metadata {
+		if instance.Zone == z.zone {
+			known++
+		}
+	}
+
+	names := local
+	switch wanted := int(math.Ceil(z.threshold * float64(known))); {
+	case len(local) == 0:
+		for name := range instances {
+			names = append(names, name)
+		}
+	case len(local) < wanted:
+		var remote []string
+		for name := range instances {
+			if metadata[name].Zone != z.zone {
+				remote = append(remote, name)
+			}
+		}
+		sort.Strings(remote)
+		if shortfall := wanted - len(local); shortfall < len(remote) {
+			remote = remote[:shortfall]
+		}
+		names = append(names, remote...)
+	}
+	sort.Strings(names)
+
+	z.endpoints = make([]endpoint.Endpoint, len(names))
+	z.instances = make(map[string]endpoint.Endpoint, len(names))
+	for i, name := range names {
+		z.endpoints[i] = instances[name]
+		z.instances[name] = instances[name]
+	}
+	z.source, z.metadata = instances, metadata
+	return nil
+}
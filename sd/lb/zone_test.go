@@ -0,0 +1,117 @@
+package lb
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestZoneAware(t *testing.T) {
+	metadata := map[string]sd.Instance{
+		"a1": {Addr: "a1", Zone: "a"},
+		"a2": {Addr: "a2", Zone: "a"},
+		"a3": {Addr: "a3", Zone: "a"},
+		"a4": {Addr: "a4", Zone: "a"},
+		"b1": {Addr: "b1", Zone: "b"},
+		"b2": {Addr: "b2", Zone: "b"},
+	}
+	s := &metadataSubscriber{metadata: metadata}
+	z := NewZoneAware(s, "a", 0.5)
+
+	for _, tc := range []struct {
+		healthy []string
+		want    []string
+	}{
+		{[]string{"a1", "a2", "a3", "a4", "b1", "b2"}, []string{"a1", "a2", "a3", "a4"}},
+		{[]string{"a1", "a2", "b1", "b2"}, []string{"a1", "a2"}},                         // exactly at the threshold
+		{[]string{"a1", "b1", "b2"}, []string{"a1", "b1"}},                               // spill over
+		{[]string{"b1"}, []string{"b1"}},                                                 // nothing local
+		{[]string{"a1", "a2", "a3", "a4", "b1", "b2"}, []string{"a1", "a2", "a3", "a4"}}, // recovered
+	} {
+		s.instances = map[string]endpoint.Endpoint{}
+		for _, name := range tc.healthy {
+			s.instances[name] = named(name)
+		}
+		assertZoneEndpoints(t, z, tc.healthy, tc.want)
+	}
+}
+
+func TestZoneAwareSpillover(t *testing.T) {
+	metadata := map[string]sd.Instance{
+		"a1": {Addr: "a1", Zone: "a"},
+		"a2": {Addr: "a2", Zone: "a"},
+		"a3": {Addr: "a3", Zone: "a"},
+		"a4": {Addr: "a4", Zone: "a"},
+		"b1": {Addr: "b1", Zone: "b"},
+		"b2": {Addr: "b2", Zone: "b"},
+		"c1": {Addr: "c1", Zone: "c"},
+	}
+	s := &metadataSubscriber{metadata: metadata}
+
+	for _, tc := range []struct {
+		threshold float64
+		healthy   []string
+		want      []string
+	}{
+		{0.75, []string{"a1", "a2", "a3", "b1", "b2", "c1"}, []string{"a1", "a2", "a3"}},             // exactly at the threshold
+		{0.75, []string{"a1", "a2", "b1", "b2", "c1"}, []string{"a1", "a2", "b1"}},                   // one short
+		{0.7, []string{"a1", "a2", "b1", "b2", "c1"}, []string{"a1", "a2", "b1"}},                    // 2.8 rounds up
+		{1, []string{"a1", "b1", "b2", "c1"}, []string{"a1", "b1", "b2", "c1"}},                      // three short
+		{1, []string{"a1", "c1"}, []string{"a1", "c1"}},                                              // not enough remote
+		{0, []string{"b1", "c1"}, []string{"b1", "c1"}},                                              // nothing local
+		{0.75, []string{"a1", "a2", "a3", "a4", "b1", "b2", "c1"}, []string{"a1", "a2", "a3", "a4"}}, // healthy
+	} {
+		s.instances = map[string]endpoint.Endpoint{}
+		for _, name := range tc.healthy {
+			s.instances[name] = named(name)
+		}
+		assertZoneEndpoints(t, NewZoneAware(s, "a", tc.threshold), tc.healthy, tc.want)
+	}
+}
+
+func TestZoneAwareUnknownZone(t *testing.T) {
+	// Instances without zone metadata are only local to a balancer without
+	// zone, and every instance is used otherwise.
+	s := &metadataSubscriber{
+		instances: map[string]endpoint.Endpoint{"x": named("x"), "y": named("y")},
+		metadata:  map[string]sd.Instance{},
+	}
+	assertZoneEndpoints(t, NewZoneAware(s, "a", 0.5), nil, []string{"x", "y"})
+	assertZoneEndpoints(t, NewZoneAware(s, "", 0.5), nil, []string{"x", "y"})
+}
+
+func TestZoneAwareStableEndpoints(t *testing.T) {
+	s := &metadataSubscriber{
+		instances: map[string]endpoint.Endpoint{"a1": named("a1"), "b1": named("b1")},
+		metadata:  map[string]sd.Instance{"a1": {Addr: "a1", Zone: "a"}, "b1": {Addr: "b1", Zone: "b"}},
+	}
+	z := NewZoneAware(s, "a", 0.5)
+	first, _ := z.Endpoints()
+	second, _ := z.Endpoints()
+	if &first[0] != &second[0] {
+		t.Error("endpoints changed without a change in instances")
+	}
+}
+
+func assertZoneEndpoints(t *testing.T, z *ZoneAware, healthy, want []string) {
+	endpoints, err := z.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, e := range endpoints {
+		response, _ := e(context.Background(), struct{}{})
+		have = append(have, response.(string))
+	}
+	if len(want) != len(have) {
+		t.Fatalf("healthy %v: want %v, have %v", healthy, want, have)
+	}
+	for i := range want {
+		if want[i] != have[i] {
+			t.Fatalf("healthy %v: want %v, have %v", healthy, want, have)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package sd
 
-import "github.com/go-kit/kit/endpoint"
+import (
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
 
 // Subscriber listens to a service discovery system and yields a set of
 // identical endpoints on demand. An error indicates a problem with connectivity
@@ -9,3 +13,65 @@ import "github.com/go-kit/kit/endpoint"
 type Subscriber interface {
 	Endpoints() ([]endpoint.Endpoint, error)
 }
+
+// InstanceSubscriber is a Subscriber that also identifies each endpoint by
+// the instance string it was created from. Balancers that route requests to
+// specific instances, rather than to any of them, use the instance strings to
+// keep routing stable as instances come and go.
+type InstanceSubscriber interface {
+	Subscriber
+	Instances() (map[string]endpoint.Endpoint, error)
+}
+
+// MetadataSubscriber is an InstanceSubscriber that also describes each
+// instance with the metadata its service discovery system provides, such as
+// weights, zones or tags. Balancers use it to prefer some instances over
+// others.
+//
+// Metadata is keyed by instance string, like Instances. It may describe
+// instances that Instances doesn't currently return, e.g. because a wrapping
+// subscriber filtered them out; consumers should look up the instances they
+// route to, and treat missing ones as having no metadata.
+type MetadataSubscriber interface {
+	InstanceSubscriber
+	Metadata() (map[string]Instance, error)
+}
+
+// StatusSubscriber is a Subscriber that also reports on the state of its
+// service discovery system, for debugging and monitoring. Unlike the errors
+// returned by Endpoints, which a subscriber may withhold while it serves
+// stale endpoints, the status reflects the most recent attempt to update.
+type StatusSubscriber interface {
+	Subscriber
+	Status() Status
+}
+
+// Status describes the state of a subscriber's service discovery system.
+type Status struct {
+	LastUpdate time.Time // of the instances; zero if they were never updated
+	Err        error     // of the most recent attempt to update; nil if it succeeded
+}
+
+// NotifyingSubscriber is a Subscriber that also pushes changes to its
+// instances to consumers that need to react to them, e.g. to warm up
+// connections, rather than poll Endpoints.
+//
+// Like signal.Notify, Notify relays events to the channel until StopNotify is
+// called with it. The first event describes the instances at the time of the
+// call. Sending to the channel doesn't block the subscriber: while a consumer
+// lags behind, changes are coalesced, so it receives the latest state, with
+// instances added and removed since the last event it received.
+type NotifyingSubscriber interface {
+	Subscriber
+	Notify(ch chan<- Event)
+	StopNotify(ch chan<- Event)
+}
+
+// Event is a change to the instances of a subscriber, or a failure of its
+// service discovery system.
+type Event struct {
+	Instances []string // all current instances, sorted; the last known-good ones if Err is set. Read-only.
+	Added     []string // since the previous event received; all instances for the first
+	Removed   []string // since the previous event received
+	Err       error    // of the most recent attempt to update; nil if it succeeded
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/samuel/go-zookeeper/zk"
@@ -37,7 +38,8 @@ type Client interface {
 	// CreateParentNodes should try to create the path in case it does not exist
 	// yet on ZooKeeper.
 	CreateParentNodes(path string) error
-	// Register a service with ZooKeeper.
+	// Register a service with ZooKeeper. The service should be registered
+	// again if its node is lost along with the session that created it.
 	Register(s *Service) error
 	// Deregister a service with ZooKeeper.
 	Deregister(s *Service) error
@@ -58,11 +60,25 @@ type clientConfig struct {
 // Option functions enable friendly APIs.
 type Option func(*clientConfig) error
 
+// zkConn is the part of *zk.Conn used by the client.
+type zkConn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	CreateProtectedEphemeralSequential(path string, data []byte, acl []zk.ACL) (string, error)
+	Exists(path string) (bool, *zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	Delete(path string, version int32) error
+	Close()
+}
+
 type client struct {
-	*zk.Conn
+	zkConn
 	clientConfig
 	active bool
 	quit   chan struct{}
+
+	mtx      sync.Mutex
+	services map[*Service]struct{} // registered
 }
 
 // ACL returns an Option specifying a non-default ACL for creating parent nodes.
@@ -164,21 +180,34 @@ func NewClient(servers []string, logger log.Logger, options ...Option) (Client,
 		}
 	}
 
-	c := &client{conn, config, true, make(chan struct{})}
-
-	// Start listening for incoming Event payloads and callback the set
-	// eventHandler.
-	go func() {
-		for {
-			select {
-			case event := <-eventc:
-				config.eventHandler(event)
-			case <-c.quit:
-				return
+	c := &client{
+		zkConn:       conn,
+		clientConfig: config,
+		active:       true,
+		quit:         make(chan struct{}),
+		services:     map[*Service]struct{}{},
+	}
+
+	go c.loop(eventc)
+	return c, nil
+}
+
+// loop listens for incoming Event payloads and calls back the set
+// eventHandler. Once a session is (re-)established, any ephemeral nodes lost
+// with an expired session are registered again. That must not block the
+// loop, which the ZooKeeper library needs drained.
+func (c *client) loop(eventc <-chan zk.Event) {
+	for {
+		select {
+		case event := <-eventc:
+			c.eventHandler(event)
+			if event.Type == zk.EventSession && event.State == zk.StateHasSession {
+				go c.reregister()
 			}
+		case <-c.quit:
+			return
 		}
-	}()
-	return c, nil
+	}
 }
 
 // CreateParentNodes implements the ZooKeeper Client interface.
@@ -229,16 +258,34 @@ func (c *client) GetEntries(path string) ([]string, <-chan zk.Event, error) {
 	return resp, eventc, nil
 }
 
-// Register implements the ZooKeeper Client interface.
+// Register implements the ZooKeeper Client interface. The client keeps track
+// of the service, and registers it again whenever it finds the node gone after
+// a new session has been established.
 func (c *client) Register(s *Service) error {
 	if s.Path[len(s.Path)-1] != '/' {
 		s.Path += "/"
 	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.create(s); err != nil {
+		return err
+	}
+	c.services[s] = struct{}{}
+	return nil
+}
+
+// create creates the ephemeral node of the service. It must be called with
+// the mutex held, as it records the node name in the service.
+func (c *client) create(s *Service) error {
+	data, err := s.payload()
+	if err != nil {
+		return err
+	}
 	path := s.Path + s.Name
 	if err := c.CreateParentNodes(path); err != nil {
 		return err
 	}
-	node, err := c.CreateProtectedEphemeralSequential(path, s.Data, c.acl)
+	node, err := c.CreateProtectedEphemeralSequential(path, data, c.acl)
 	if err != nil {
 		return err
 	}
@@ -246,22 +293,47 @@ func (c *client) Register(s *Service) error {
 	return nil
 }
 
+// reregister creates the nodes of registered services that no longer exist,
+// having been deleted by ZooKeeper when the session that created them
+// expired.
+func (c *client) reregister() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for s := range c.services {
+		found, _, err := c.Exists(s.node)
+		if err == nil && found {
+			continue
+		}
+		if err == nil {
+			err = c.create(s)
+		}
+		if err != nil {
+			c.logger.Log("service", s.Name, "action", "reregister", "err", err)
+			continue
+		}
+		c.logger.Log("service", s.Name, "action", "reregister", "node", s.node)
+	}
+}
+
 // Deregister implements the ZooKeeper Client interface.
 func (c *client) Deregister(s *Service) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	if s.node == "" {
 		return ErrNotRegistered
 	}
-	path := s.Path + s.Name
-	found, stat, err := c.Exists(path)
+	delete(c.services, s)
+	found, stat, err := c.Exists(s.node)
 	if err != nil {
 		return err
 	}
 	if !found {
 		return ErrNodeNotFound
 	}
-	if err := c.Delete(path, stat.Version); err != nil {
+	if err := c.Delete(s.node, stat.Version); err != nil {
 		return err
 	}
+	s.node = ""
 	return nil
 }
 
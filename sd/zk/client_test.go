@@ -2,6 +2,9 @@ package zk
 
 import (
 	"bytes"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -74,6 +77,153 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestReregisterAfterSessionExpiry(t *testing.T) {
+	var (
+		conn   = newFakeConn()
+		events = make(chan stdzk.Event)
+		c      = &client{
+			zkConn:       conn,
+			clientConfig: clientConfig{acl: DefaultACL, logger: log.NewNopLogger(), eventHandler: func(stdzk.Event) {}},
+			active:       true,
+			quit:         make(chan struct{}),
+			services:     map[*Service]struct{}{},
+		}
+		a    = &Service{Path: "/svc", Name: "a", Data: []byte("10.0.0.1:80")}
+		b    = &Service{Path: "/svc", Name: "b", Data: []byte("10.0.0.2:80")}
+		gone = &Service{Path: "/svc", Name: "gone", Data: []byte("10.0.0.3:80")}
+	)
+	go c.loop(events)
+	defer c.Stop()
+
+	for _, s := range []*Service{a, b, gone} {
+		if err := c.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Deregister(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	// A session that's merely reconnected still has its nodes.
+	events <- stdzk.Event{Type: stdzk.EventSession, State: stdzk.StateHasSession}
+	time.Sleep(10 * time.Millisecond)
+	if want, have := 3, conn.creates(); want != have {
+		t.Errorf("want %d creates, have %d", want, have)
+	}
+
+	// An expired one lost them.
+	conn.expire()
+	events <- stdzk.Event{Type: stdzk.EventSession, State: stdzk.StateExpired}
+	events <- stdzk.Event{Type: stdzk.EventSession, State: stdzk.StateHasSession}
+	want := fmt.Sprint([]string{"/svc/a", "/svc/b"})
+	deadline := time.Now().Add(time.Second)
+	for fmt.Sprint(conn.registered()) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("want %s, have %v", want, conn.registered())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // and nothing else
+	if want, have := 5, conn.creates(); want != have {
+		t.Errorf("want %d creates, have %d", want, have)
+	}
+}
+
+// fakeConn keeps nodes like ZooKeeper, in a single session.
+type fakeConn struct {
+	mtx       sync.Mutex
+	nodes     map[string]bool // by path; true if ephemeral
+	seq       int
+	ephemeral int // created
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{nodes: map[string]bool{}}
+}
+
+func (c *fakeConn) Create(path string, _ []byte, _ int32, _ []stdzk.ACL) (string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.nodes[path]; ok {
+		return "", stdzk.ErrNodeExists
+	}
+	c.nodes[path] = false
+	return path, nil
+}
+
+func (c *fakeConn) CreateProtectedEphemeralSequential(path string, _ []byte, _ []stdzk.ACL) (string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.seq++
+	c.ephemeral++
+	node := fmt.Sprintf("%s%010d", path, c.seq)
+	c.nodes[node] = true
+	return node, nil
+}
+
+func (c *fakeConn) Exists(path string) (bool, *stdzk.Stat, error) {
+	return c.exists(path), &stdzk.Stat{}, nil
+}
+
+func (c *fakeConn) Get(string) ([]byte, *stdzk.Stat, error) {
+	return nil, nil, stdzk.ErrNoNode
+}
+
+func (c *fakeConn) ChildrenW(string) ([]string, *stdzk.Stat, <-chan stdzk.Event, error) {
+	return nil, nil, nil, stdzk.ErrNoNode
+}
+
+func (c *fakeConn) Delete(path string, _ int32) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.nodes[path]; !ok {
+		return stdzk.ErrNoNode
+	}
+	delete(c.nodes, path)
+	return nil
+}
+
+func (c *fakeConn) Close() {}
+
+func (c *fakeConn) exists(path string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	_, ok := c.nodes[path]
+	return ok
+}
+
+// expire deletes the ephemeral nodes, like an expired session does.
+func (c *fakeConn) expire() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for path, ephemeral := range c.nodes {
+		if ephemeral {
+			delete(c.nodes, path)
+		}
+	}
+}
+
+// creates returns the number of ephemeral nodes created.
+func (c *fakeConn) creates() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.ephemeral
+}
+
+// registered returns the services with an ephemeral node, by path.
+func (c *fakeConn) registered() []string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var paths []string
+	for path, ephemeral := range c.nodes {
+		if ephemeral {
+			paths = append(paths, path[:len(path)-10])
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 func TestOptions(t *testing.T) {
 	_, err := NewClient([]string{"localhost"}, log.NewNopLogger(), Credentials("valid", "credentials"))
 	if err != nil && err != stdzk.ErrNoServer {
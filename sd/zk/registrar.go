@@ -1,6 +1,11 @@
 package zk
 
-import "github.com/go-kit/kit/log"
+import (
+	"encoding/json"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
 
 // Registrar registers service instance liveness information to ZooKeeper.
 type Registrar struct {
@@ -11,28 +16,61 @@ type Registrar struct {
 
 // Service holds the root path, service name and instance identifying data you
 // want to publish to ZooKeeper.
+//
+// The instance is either described by Data, stored as is, or by Instance,
+// stored as a JSON payload that carries its metadata as well. Subscribers
+// understand both.
 type Service struct {
-	Path string // discovery namespace, example: /myorganization/myplatform/
-	Name string // service name, example: addscv
-	Data []byte // instance data to store for discovery, example: 10.0.2.10:80
-	node string // Client will record the ephemeral node name so we can deregister
+	Path     string       // discovery namespace, example: /myorganization/myplatform/
+	Name     string       // service name, example: addscv
+	Data     []byte       // instance data to store for discovery, example: 10.0.2.10:80
+	Instance *sd.Instance // takes precedence over Data, example: &sd.Instance{Addr: "10.0.2.10:80", Zone: "eu-west-1a"}
+	node     string       // Client will record the ephemeral node name so we can deregister
+}
+
+// payload is the JSON document stored in the node of a Service with an
+// Instance, example: {"address":"10.0.2.10:80","zone":"eu-west-1a"}
+type payload struct {
+	Address string   `json:"address"`
+	Weight  int      `json:"weight,omitempty"`
+	Zone    string   `json:"zone,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// payload returns the data to store in the node of the service.
+func (s *Service) payload() ([]byte, error) {
+	if s.Instance == nil {
+		return s.Data, nil
+	}
+	return json.Marshal(payload{
+		Address: s.Instance.Addr,
+		Weight:  s.Instance.Weight,
+		Zone:    s.Instance.Zone,
+		Tags:    s.Instance.Tags,
+	})
 }
 
 // NewRegistrar returns a ZooKeeper Registrar acting on the provided catalog
 // registration.
 func NewRegistrar(client Client, service Service, logger log.Logger) *Registrar {
+	data := string(service.Data)
+	if service.Instance != nil {
+		data = service.Instance.Addr
+	}
 	return &Registrar{
 		client:  client,
 		service: service,
 		logger: log.NewContext(logger).With(
 			"service", service.Name,
 			"path", service.Path,
-			"data", string(service.Data),
+			"data", data,
 		),
 	}
 }
 
-// Register implements sd.Registrar interface.
+// Register implements sd.Registrar interface. The client registers the service
+// again if it's lost along with an expired ZooKeeper session, until Deregister
+// is called.
 func (r *Registrar) Register() {
 	if err := r.client.Register(&r.service); err != nil {
 		r.logger.Log("err", err)
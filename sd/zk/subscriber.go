@@ -1,6 +1,10 @@
 package zk
 
 import (
+	"encoding/json"
+	"strings"
+	"time"
+
 	"github.com/samuel/go-zookeeper/zk"
 
 	"github.com/go-kit/kit/endpoint"
@@ -9,8 +13,14 @@ import (
 	"github.com/go-kit/kit/sd/cache"
 )
 
+// retryInterval is how long the Subscriber waits to retrieve the entries again
+// after failing to, e.g. while the client is reconnecting.
+const retryInterval = time.Second
+
 // Subscriber yield endpoints stored in a certain ZooKeeper path. Any kind of
 // change in that path is watched and will update the Subscriber endpoints.
+// Node data is either an instance string, or a JSON payload with the instance
+// address and metadata, as stored for a Service with an Instance.
 type Subscriber struct {
 	client Client
 	path   string
@@ -19,15 +29,20 @@ type Subscriber struct {
 	quitc  chan struct{}
 }
 
-var _ sd.Subscriber = &Subscriber{}
+var (
+	_ sd.MetadataSubscriber  = &Subscriber{}
+	_ sd.StatusSubscriber    = &Subscriber{}
+	_ sd.NotifyingSubscriber = &Subscriber{}
+)
 
 // NewSubscriber returns a ZooKeeper subscriber. ZooKeeper will start watching
-// the given path for changes and update the Subscriber endpoints.
-func NewSubscriber(c Client, path string, factory sd.Factory, logger log.Logger) (*Subscriber, error) {
+// the given path for changes and update the Subscriber endpoints. The options
+// configure the endpoint cache.
+func NewSubscriber(c Client, path string, factory sd.Factory, logger log.Logger, options ...cache.Option) (*Subscriber, error) {
 	s := &Subscriber{
 		client: c,
 		path:   path,
-		cache:  cache.New(factory, logger),
+		cache:  cache.New(factory, logger, options...),
 		logger: logger,
 		quitc:  make(chan struct{}),
 	}
@@ -43,7 +58,7 @@ func NewSubscriber(c Client, path string, factory sd.Factory, logger log.Logger)
 		return nil, err
 	}
 	logger.Log("path", s.path, "instances", len(instances))
-	s.cache.Update(instances)
+	s.cache.UpdateInstances(makeInstances(instances))
 
 	go s.loop(eventc)
 
@@ -53,31 +68,88 @@ func NewSubscriber(c Client, path string, factory sd.Factory, logger log.Logger)
 func (s *Subscriber) loop(eventc <-chan zk.Event) {
 	var (
 		instances []string
+		retryc    <-chan time.Time
 		err       error
 	)
 	for {
 		select {
 		case <-eventc:
-			// We received a path update notification. Call GetEntries to
-			// retrieve child node data, and set a new watch, as ZK watches are
-			// one-time triggers.
-			instances, eventc, err = s.client.GetEntries(s.path)
-			if err != nil {
-				s.logger.Log("path", s.path, "msg", "failed to retrieve entries", "err", err)
-				continue
-			}
-			s.logger.Log("path", s.path, "instances", len(instances))
-			s.cache.Update(instances)
-
+		case <-retryc:
 		case <-s.quitc:
 			return
 		}
+
+		// We received a path update notification. Call GetEntries to
+		// retrieve child node data, and set a new watch, as ZK watches are
+		// one-time triggers.
+		instances, eventc, err = s.client.GetEntries(s.path)
+		retryc = nil
+		if err != nil {
+			s.logger.Log("path", s.path, "msg", "failed to retrieve entries", "err", err)
+			s.cache.SetError(err)
+			if eventc == nil {
+				// No watch was set, so nothing would trigger the next
+				// attempt.
+				retryc = time.After(retryInterval)
+			}
+			continue
+		}
+		s.logger.Log("path", s.path, "instances", len(instances))
+		s.cache.UpdateInstances(makeInstances(instances))
+	}
+}
+
+// makeInstances parses the node data of the entries. Data that isn't a JSON
+// payload is taken to be the instance string.
+func makeInstances(entries []string) []sd.Instance {
+	instances := make([]sd.Instance, len(entries))
+	for i, entry := range entries {
+		instances[i] = sd.Instance{Addr: entry}
+		if !strings.HasPrefix(strings.TrimSpace(entry), "{") {
+			continue
+		}
+		var p payload
+		if err := json.Unmarshal([]byte(entry), &p); err != nil || p.Address == "" {
+			continue
+		}
+		instances[i] = sd.Instance{
+			Addr:   p.Address,
+			Weight: p.Weight,
+			Zone:   p.Zone,
+			Tags:   p.Tags,
+		}
 	}
+	return instances
 }
 
 // Endpoints implements the Subscriber interface.
 func (s *Subscriber) Endpoints() ([]endpoint.Endpoint, error) {
-	return s.cache.Endpoints(), nil
+	return s.cache.Endpoints()
+}
+
+// Instances implements the InstanceSubscriber interface.
+func (s *Subscriber) Instances() (map[string]endpoint.Endpoint, error) {
+	return s.cache.Instances()
+}
+
+// Metadata implements the MetadataSubscriber interface.
+func (s *Subscriber) Metadata() (map[string]sd.Instance, error) {
+	return s.cache.Metadata(), nil
+}
+
+// Status implements the StatusSubscriber interface.
+func (s *Subscriber) Status() sd.Status {
+	return s.cache.Status()
+}
+
+// Notify implements the NotifyingSubscriber interface.
+func (s *Subscriber) Notify(ch chan<- sd.Event) {
+	s.cache.Notify(ch)
+}
+
+// StopNotify implements the NotifyingSubscriber interface.
+func (s *Subscriber) StopNotify(ch chan<- sd.Event) {
+	s.cache.StopNotify(ch)
 }
 
 // Stop terminates the Subscriber.
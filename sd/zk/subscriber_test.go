@@ -1,8 +1,11 @@
 package zk
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/go-kit/kit/sd"
 )
 
 func TestSubscriber(t *testing.T) {
@@ -115,3 +118,86 @@ func TestBadSubscriberCreate(t *testing.T) {
 		t.Error("expected Subscriber not to be created")
 	}
 }
+
+func TestPayload(t *testing.T) {
+	service := Service{
+		Path:     path,
+		Name:     "instance1",
+		Data:     []byte("ignored"),
+		Instance: &sd.Instance{Addr: "10.0.2.10:80", Weight: 2, Zone: "eu-west-1a", Tags: []string{"canary"}},
+	}
+	data, err := service.payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instances := makeInstances([]string{string(data), "10.0.2.11:80", "{not json"})
+	if want, have := 3, len(instances); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if have := instances[0]; have.Addr != "10.0.2.10:80" || have.Weight != 2 || have.Zone != "eu-west-1a" || !have.HasTag("canary") {
+		t.Errorf("want %+v, have %+v", *service.Instance, have)
+	}
+	for i, want := range []string{"10.0.2.10:80", "10.0.2.11:80", "{not json"} {
+		if have := instances[i].Addr; want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestSubscriberPayload(t *testing.T) {
+	client := newFakeClient()
+
+	s, err := NewSubscriber(client, path, newFactory(""), logger)
+	if err != nil {
+		t.Fatalf("failed to create new Subscriber: %v", err)
+	}
+	defer s.Stop()
+
+	client.AddService(path+"/instance1", `{"address":"10.0.2.10:80","zone":"eu-west-1a"}`)
+	if err = asyncTest(100*time.Millisecond, 1, s); err != nil {
+		t.Fatal(err)
+	}
+
+	instances, err := s.Instances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := instances["10.0.2.10:80"]; !ok {
+		t.Errorf("want instance 10.0.2.10:80, have %v", instances)
+	}
+	metadata, err := s.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "eu-west-1a", metadata["10.0.2.10:80"].Zone; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestSubscriberNotify(t *testing.T) {
+	client := newFakeClient()
+
+	s, err := NewSubscriber(client, path, newFactory(""), logger)
+	if err != nil {
+		t.Fatalf("failed to create new Subscriber: %v", err)
+	}
+	defer s.Stop()
+
+	events := make(chan sd.Event, 1)
+	s.Notify(events)
+	defer s.StopNotify(events)
+	if e := <-events; len(e.Instances) != 0 {
+		t.Errorf("want no instances, have %v", e.Instances)
+	}
+
+	client.AddService(path+"/instance1", "10.0.2.10:80")
+	select {
+	case e := <-events:
+		if want, have := "10.0.2.10:80", strings.Join(e.Added, ","); want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	case <-time.After(time.Second):
+		t.Error("want event, have none")
+	}
+}
@@ -0,0 +1,19 @@
+package zipkin
+
+import "errors"
+
+// Collector receives finished, sampled spans from a Tracer and reports them to
+// Zipkin. Implementations must be safe for concurrent use, and should not
+// block the caller on network I/O.
+type Collector interface {
+	Collect(SpanModel) error
+	Close() error
+}
+
+// ErrQueueFull is returned by a Collector that can't accept more spans until
+// it has reported those it already holds. The span is dropped.
+var ErrQueueFull = errors.New("zipkin: collector queue full")
+
+// ErrCollectorClosed is returned by a Collector that has been closed. The span
+// is dropped.
+var ErrCollectorClosed = errors.New("zipkin: collector closed")
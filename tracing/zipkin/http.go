@@ -0,0 +1,205 @@
+package zipkin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// HTTPCollector is a Collector that reports spans to the Zipkin v2 HTTP API,
+// e.g. http://localhost:9411/api/v2/spans, as JSON.
+//
+// Spans are queued, and sent in batches from a separate goroutine: whenever
+// a full batch has been queued, and at a regular interval otherwise. The queue
+// is bounded; when it's full, new spans are dropped, and Collect returns
+// ErrQueueFull. Batches that can't be delivered are logged and dropped.
+type HTTPCollector struct {
+	url           string
+	client        *http.Client
+	batchSize     int
+	batchInterval time.Duration
+	maxQueueSize  int
+	logger        log.Logger
+
+	mtx    sync.Mutex
+	queue  []SpanModel
+	closed bool
+
+	batchc    chan struct{}
+	quitc     chan struct{}
+	donec     chan struct{}
+	closeOnce sync.Once
+}
+
+// NewHTTPCollector returns a new HTTPCollector reporting to url, and starts
+// its reporting goroutine. Close the collector to flush queued spans and stop
+// the goroutine. An error is returned if an option is out of range.
+func NewHTTPCollector(url string, options ...HTTPOption) (*HTTPCollector, error) {
+	c := &HTTPCollector{
+		url:           url,
+		client:        &http.Client{Timeout: 5 * time.Second},
+		batchSize:     100,
+		batchInterval: time.Second,
+		maxQueueSize:  1000,
+		logger:        log.NewNopLogger(),
+		batchc:        make(chan struct{}, 1),
+		quitc:         make(chan struct{}),
+		donec:         make(chan struct{}),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	switch {
+	case c.batchSize <= 0:
+		return nil, fmt.Errorf("zipkin: invalid batch size %d", c.batchSize)
+	case c.batchInterval <= 0:
+		return nil, fmt.Errorf("zipkin: invalid batch interval %s", c.batchInterval)
+	case c.maxQueueSize <= 0:
+		return nil, fmt.Errorf("zipkin: invalid max queue size %d", c.maxQueueSize)
+	}
+	go c.loop()
+	return c, nil
+}
+
+// HTTPOption sets an optional parameter for the HTTPCollector.
+type HTTPOption func(*HTTPCollector)
+
+// HTTPClient sets the client used to send spans. By default, a client with a
+// 5 second timeout is used.
+func HTTPClient(client *http.Client) HTTPOption {
+	return func(c *HTTPCollector) { c.client = client }
+}
+
+// HTTPBatchSize sets the maximum number of spans sent in a single request.
+// It must be positive. The default is 100.
+func HTTPBatchSize(n int) HTTPOption {
+	return func(c *HTTPCollector) { c.batchSize = n }
+}
+
+// HTTPBatchInterval sets the maximum time a span waits in the queue before
+// it's sent. It must be positive. The default is 1 second.
+func HTTPBatchInterval(d time.Duration) HTTPOption {
+	return func(c *HTTPCollector) { c.batchInterval = d }
+}
+
+// HTTPMaxQueueSize sets the maximum number of spans waiting to be sent. It
+// must be positive. The default is 1000.
+func HTTPMaxQueueSize(n int) HTTPOption {
+	return func(c *HTTPCollector) { c.maxQueueSize = n }
+}
+
+// HTTPLogger sets the logger used to report failed requests. By default, no
+// errors are logged.
+func HTTPLogger(logger log.Logger) HTTPOption {
+	return func(c *HTTPCollector) { c.logger = logger }
+}
+
+// Collect implements Collector. Once the collector is closed, spans are
+// dropped, and ErrCollectorClosed is returned.
+func (c *HTTPCollector) Collect(m SpanModel) error {
+	c.mtx.Lock()
+	if c.closed {
+		c.mtx.Unlock()
+		return ErrCollectorClosed
+	}
+	if len(c.queue) >= c.maxQueueSize {
+		c.mtx.Unlock()
+		return ErrQueueFull
+	}
+	c.queue = append(c.queue, m)
+	full := len(c.queue) >= c.batchSize
+	c.mtx.Unlock()
+
+	if full {
+		select {
+		case c.batchc <- struct{}{}:
+		default: // a flush is already pending
+		}
+	}
+	return nil
+}
+
+// Close implements Collector. It sends all queued spans, and waits for the
+// reporting goroutine to exit.
+func (c *HTTPCollector) Close() error {
+	c.closeOnce.Do(func() {
+		c.mtx.Lock()
+		c.closed = true
+		c.mtx.Unlock()
+		close(c.quitc)
+	})
+	<-c.donec
+	return nil
+}
+
+func (c *HTTPCollector) loop() {
+	defer close(c.donec)
+
+	ticker := time.NewTicker(c.batchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.flush()
+		case <-c.batchc:
+			c.flush()
+		case <-c.quitc:
+			c.flush()
+			return
+		}
+	}
+}
+
+// flush sends queued spans in batches until the queue is empty.
+func (c *HTTPCollector) flush() {
+	for {
+		c.mtx.Lock()
+		n := len(c.queue)
+		if n > c.batchSize {
+			n = c.batchSize
+		}
+		batch := make([]SpanModel, n)
+		copy(batch, c.queue)
+		c.queue = append(c.queue[:0], c.queue[n:]...)
+		c.mtx.Unlock()
+
+		if n == 0 {
+			return
+		}
+		if err := c.send(batch); err != nil {
+			c.logger.Log("err", err, "dropped", n)
+		}
+	}
+}
+
+func (c *HTTPCollector) send(batch []SpanModel) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("zipkin collector responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package zipkin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/tracing/zipkin"
+)
+
+func TestHTTPCollectorBatches(t *testing.T) {
+	var (
+		mtx     sync.Mutex
+		batches [][]zipkin.SpanModel
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []zipkin.SpanModel
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Error(err)
+		}
+		mtx.Lock()
+		batches = append(batches, batch)
+		mtx.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	c, err := zipkin.NewHTTPCollector(server.URL, zipkin.HTTPBatchSize(2), zipkin.HTTPBatchInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := c.Collect(zipkin.SpanModel{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Close() // flushes the partial batch
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if want, have := 2, len(batches); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := 2, len(batches[0]); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "c", batches[1][0].Name; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestHTTPCollectorQueueFull(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	c, err := zipkin.NewHTTPCollector(server.URL, zipkin.HTTPMaxQueueSize(1), zipkin.HTTPBatchInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Collect(zipkin.SpanModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := zipkin.ErrQueueFull, c.Collect(zipkin.SpanModel{}); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestHTTPCollectorInvalidOptions(t *testing.T) {
+	for _, option := range []zipkin.HTTPOption{
+		zipkin.HTTPBatchSize(0),
+		zipkin.HTTPBatchInterval(0),
+		zipkin.HTTPMaxQueueSize(-1),
+	} {
+		if _, err := zipkin.NewHTTPCollector("http://localhost:9411/api/v2/spans", option); err == nil {
+			t.Error("want error, have none")
+		}
+	}
+}
+
+func TestHTTPCollectorClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	c, err := zipkin.NewHTTPCollector(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if want, have := zipkin.ErrCollectorClosed, c.Collect(zipkin.SpanModel{}); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,37 @@
+package zipkin
+
+// SpanModel is a span in the Zipkin v2 model, as accepted by the JSON API of
+// a Zipkin collector. Timestamps and durations are in microseconds.
+type SpanModel struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId,omitempty"`
+	Name           string            `json:"name"`
+	Kind           string            `json:"kind,omitempty"`
+	Timestamp      int64             `json:"timestamp"`
+	Duration       int64             `json:"duration"`
+	Debug          bool              `json:"debug,omitempty"`
+	LocalEndpoint  *Endpoint         `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *Endpoint         `json:"remoteEndpoint,omitempty"`
+	Annotations    []Annotation      `json:"annotations,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+// Endpoint identifies a network participant in a span, either the local
+// service or the remote peer.
+type Endpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        uint16 `json:"port,omitempty"`
+}
+
+// Annotation is a timestamped event within a span.
+type Annotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+func (e *Endpoint) empty() bool {
+	return e.ServiceName == "" && e.IPv4 == "" && e.IPv6 == "" && e.Port == 0
+}
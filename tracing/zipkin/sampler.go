@@ -0,0 +1,23 @@
+package zipkin
+
+import "math"
+
+// Sampler decides whether a new trace is recorded, given its trace ID. The
+// decision is made once, by the service that starts the trace, and is then
+// propagated to all downstream services along with the trace.
+type Sampler func(traceID uint64) bool
+
+// NewRateSampler returns a Sampler that records approximately the given
+// fraction of traces. A rate of 0 or less records nothing, and a rate of 1 or
+// more records everything. Trace IDs are random, so the decision is made by
+// comparing the ID to a boundary, and is the same for a given ID everywhere.
+func NewRateSampler(rate float64) Sampler {
+	switch {
+	case rate <= 0:
+		return func(uint64) bool { return false }
+	case rate >= 1:
+		return func(uint64) bool { return true }
+	}
+	boundary := uint64(rate * math.MaxUint64)
+	return func(traceID uint64) bool { return traceID < boundary }
+}
@@ -0,0 +1,184 @@
+package zipkin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// span implements opentracing.Span. Unsampled spans are still created, so
+// that their context can be propagated, but they're not collected.
+type span struct {
+	tracer      *Tracer
+	traceIDHigh uint64
+	traceID     uint64
+	id          uint64
+	parentID    uint64
+	sampled     bool
+	debug       bool
+
+	mtx      sync.Mutex
+	name     string
+	start    time.Time
+	tags     map[string]interface{}
+	logs     []opentracing.LogData
+	baggage  map[string]string
+	finished bool
+}
+
+func (s *span) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	finish := opts.FinishTime
+	if finish.IsZero() {
+		finish = time.Now()
+	}
+
+	s.mtx.Lock()
+	if s.finished {
+		s.mtx.Unlock()
+		return
+	}
+	s.finished = true
+	s.logs = append(s.logs, opts.BulkLogData...)
+	if !s.sampled {
+		s.mtx.Unlock()
+		return
+	}
+	m := s.model(finish)
+	s.mtx.Unlock()
+
+	s.tracer.collect(m)
+}
+
+func (s *span) SetOperationName(operationName string) opentracing.Span {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.name = operationName
+	return s
+}
+
+func (s *span) SetTag(key string, value interface{}) opentracing.Span {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.tags[key] = value
+	return s
+}
+
+func (s *span) LogEvent(event string) {
+	s.Log(opentracing.LogData{Event: event})
+}
+
+func (s *span) LogEventWithPayload(event string, payload interface{}) {
+	s.Log(opentracing.LogData{Event: event, Payload: payload})
+}
+
+func (s *span) Log(data opentracing.LogData) {
+	if data.Timestamp.IsZero() {
+		data.Timestamp = time.Now()
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.logs = append(s.logs, data)
+}
+
+func (s *span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.baggage[strings.ToLower(restrictedKey)] = value
+	return s
+}
+
+func (s *span) BaggageItem(restrictedKey string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.baggage[strings.ToLower(restrictedKey)]
+}
+
+func (s *span) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// model converts the span to the Zipkin model. Well-known OpenTracing tags
+// are mapped to the kind and remote endpoint of the span; all other tags are
+// reported as strings. Log events become annotations; payloads are reported
+// only for events without a name. It must be called with the mutex held.
+func (s *span) model(finish time.Time) SpanModel {
+	local := s.tracer.local
+	m := SpanModel{
+		TraceID:       formatTraceID(s.traceIDHigh, s.traceID),
+		ID:            formatID(s.id),
+		Name:          s.name,
+		Timestamp:     s.start.UnixNano() / int64(time.Microsecond),
+		Duration:      int64(finish.Sub(s.start) / time.Microsecond),
+		Debug:         s.debug,
+		LocalEndpoint: &local,
+	}
+	if s.parentID != 0 {
+		m.ParentID = formatID(s.parentID)
+	}
+	if m.Duration < 1 {
+		m.Duration = 1 // Zipkin treats zero as unknown
+	}
+
+	var remote Endpoint
+	for k, v := range s.tags {
+		value := fmt.Sprint(v)
+		switch k {
+		case "span.kind":
+			switch value {
+			case "client", "server", "producer", "consumer":
+				m.Kind = strings.ToUpper(value)
+				continue
+			}
+		case "peer.service":
+			remote.ServiceName = value
+			continue
+		case "peer.hostname", "peer.ipv6":
+			if setIP(&remote, value) {
+				continue
+			}
+		case "peer.ipv4":
+			if ip, ok := v.(uint32); ok {
+				remote.IPv4 = fmt.Sprintf("%d.%d.%d.%d", byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip))
+				continue
+			}
+			if setIP(&remote, value) {
+				continue
+			}
+		case "peer.port":
+			if port, err := strconv.ParseUint(value, 10, 16); err == nil {
+				remote.Port = uint16(port)
+				continue
+			}
+		}
+		if m.Tags == nil {
+			m.Tags = map[string]string{}
+		}
+		m.Tags[k] = value
+	}
+	if !remote.empty() {
+		m.RemoteEndpoint = &remote
+	}
+	if local.empty() {
+		m.LocalEndpoint = nil
+	}
+
+	for _, l := range s.logs {
+		value := l.Event
+		if value == "" && l.Payload != nil {
+			value = fmt.Sprint(l.Payload)
+		}
+		m.Annotations = append(m.Annotations, Annotation{
+			Timestamp: l.Timestamp.UnixNano() / int64(time.Microsecond),
+			Value:     value,
+		})
+	}
+	return m
+}
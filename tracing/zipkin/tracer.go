@@ -0,0 +1,299 @@
+package zipkin
+
+import (
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/go-kit/kit/log"
+)
+
+// B3 propagation headers, as understood by Zipkin instrumentation in other
+// languages. Baggage items are propagated with a separate prefix.
+const (
+	traceIDHeader      = "x-b3-traceid"
+	spanIDHeader       = "x-b3-spanid"
+	parentSpanIDHeader = "x-b3-parentspanid"
+	sampledHeader      = "x-b3-sampled"
+	flagsHeader        = "x-b3-flags"
+	baggagePrefix      = "ot-baggage-"
+)
+
+// Tracer is an opentracing.Tracer that records spans in the Zipkin model, and
+// passes them to a Collector when they're finished. Spans are propagated in
+// the TextMap format, using B3 headers, so a Tracer can join traces started by
+// other Zipkin-instrumented services, and vice versa.
+//
+// When joining a trace, the Tracer starts a new child span, rather than
+// sharing the span of the caller. That is, each side of an RPC records its own
+// span, which is what the TraceServer and TraceClient middlewares in package
+// tracing/opentracing expect.
+type Tracer struct {
+	collector Collector
+	local     Endpoint
+	sampler   Sampler
+	logger    log.Logger
+
+	mtx  sync.Mutex
+	rand *rand.Rand
+}
+
+var _ opentracing.Tracer = &Tracer{}
+
+// NewTracer returns a Tracer that reports spans to the collector on behalf of
+// the named service. By default, every trace is sampled.
+func NewTracer(c Collector, serviceName string, options ...TracerOption) *Tracer {
+	t := &Tracer{
+		collector: c,
+		local:     Endpoint{ServiceName: serviceName},
+		sampler:   NewRateSampler(1),
+		logger:    log.NewNopLogger(),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// TracerOption sets an optional parameter for the Tracer.
+type TracerOption func(*Tracer)
+
+// TracerHostPort sets the address of the local service, which is reported as
+// the local endpoint of every span. The host must be an IP address; anything
+// else is ignored.
+func TracerHostPort(hostPort string) TracerOption {
+	return func(t *Tracer) {
+		host, portString, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			return
+		}
+		setIP(&t.local, host)
+		if port, err := strconv.ParseUint(portString, 10, 16); err == nil {
+			t.local.Port = uint16(port)
+		}
+	}
+}
+
+// TracerSampler sets the Sampler used to decide whether new traces are
+// recorded. Joined traces follow the decision of the caller.
+func TracerSampler(s Sampler) TracerOption {
+	return func(t *Tracer) { t.sampler = s }
+}
+
+// TracerLogger sets the logger used to report errors, e.g. spans that the
+// collector couldn't accept. By default, no errors are logged.
+func TracerLogger(logger log.Logger) TracerOption {
+	return func(t *Tracer) { t.logger = logger }
+}
+
+// StartSpan implements opentracing.Tracer.
+func (t *Tracer) StartSpan(operationName string) opentracing.Span {
+	return t.StartSpanWithOptions(opentracing.StartSpanOptions{
+		OperationName: operationName,
+	})
+}
+
+// StartSpanWithOptions implements opentracing.Tracer. If the parent span was
+// not created by a Zipkin Tracer, it's ignored, and a new trace is started.
+func (t *Tracer) StartSpanWithOptions(opts opentracing.StartSpanOptions) opentracing.Span {
+	start := opts.StartTime
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	s := &span{
+		tracer:  t,
+		name:    opts.OperationName,
+		start:   start,
+		tags:    map[string]interface{}{},
+		baggage: map[string]string{},
+	}
+	if parent, ok := opts.Parent.(*span); ok {
+		parent.mtx.Lock()
+		s.traceIDHigh = parent.traceIDHigh
+		s.traceID = parent.traceID
+		s.parentID = parent.id
+		s.sampled = parent.sampled
+		s.debug = parent.debug
+		for k, v := range parent.baggage {
+			s.baggage[k] = v
+		}
+		parent.mtx.Unlock()
+		s.id = t.randomID()
+	} else {
+		s.traceID = t.randomID()
+		s.id = s.traceID
+		s.sampled = t.sampler(s.traceID)
+	}
+	for k, v := range opts.Tags {
+		s.tags[k] = v
+	}
+	return s
+}
+
+// Inject implements opentracing.Tracer. Only the TextMap format is supported.
+func (t *Tracer) Inject(sp opentracing.Span, format interface{}, carrier interface{}) error {
+	s, ok := sp.(*span)
+	if !ok {
+		return opentracing.ErrInvalidSpan
+	}
+	if format != opentracing.TextMap {
+		return opentracing.ErrUnsupportedFormat
+	}
+	w, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	w.Set(traceIDHeader, formatTraceID(s.traceIDHigh, s.traceID))
+	w.Set(spanIDHeader, formatID(s.id))
+	if s.parentID != 0 {
+		w.Set(parentSpanIDHeader, formatID(s.parentID))
+	}
+	if s.sampled {
+		w.Set(sampledHeader, "1")
+	} else {
+		w.Set(sampledHeader, "0")
+	}
+	if s.debug {
+		w.Set(flagsHeader, "1")
+	}
+	for k, v := range s.baggage {
+		w.Set(baggagePrefix+k, v)
+	}
+	return nil
+}
+
+// Join implements opentracing.Tracer. Only the TextMap format is supported.
+// The returned span is a child of the span found in the carrier.
+func (t *Tracer) Join(operationName string, format interface{}, carrier interface{}) (opentracing.Span, error) {
+	if format != opentracing.TextMap {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+	r, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	var (
+		traceID, spanID, sampled, flags string
+		baggage                         = map[string]string{}
+	)
+	if err := r.ForeachKey(func(key, val string) error {
+		switch key = strings.ToLower(key); {
+		case key == traceIDHeader:
+			traceID = val
+		case key == spanIDHeader:
+			spanID = val
+		case key == sampledHeader:
+			sampled = val
+		case key == flagsHeader:
+			flags = val
+		case strings.HasPrefix(key, baggagePrefix):
+			baggage[strings.TrimPrefix(key, baggagePrefix)] = val
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if traceID == "" && spanID == "" {
+		return nil, opentracing.ErrTraceNotFound
+	}
+
+	s := &span{
+		tracer:  t,
+		name:    operationName,
+		start:   time.Now(),
+		tags:    map[string]interface{}{},
+		baggage: baggage,
+		debug:   flags == "1",
+	}
+	var err error
+	if s.traceIDHigh, s.traceID, err = parseTraceID(traceID); err != nil {
+		return nil, opentracing.ErrTraceCorrupted
+	}
+	if s.parentID, err = parseID(spanID); err != nil {
+		return nil, opentracing.ErrTraceCorrupted
+	}
+	switch sampled {
+	case "1", "true":
+		s.sampled = true
+	case "0", "false":
+		s.sampled = false
+	default:
+		s.sampled = t.sampler(s.traceID)
+	}
+	if s.debug {
+		s.sampled = true
+	}
+	s.id = t.randomID()
+	return s, nil
+}
+
+func (t *Tracer) randomID() uint64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for {
+		// Zero is reserved to mean "no ID".
+		if id := uint64(t.rand.Int63())<<1 | uint64(t.rand.Int63()&1); id != 0 {
+			return id
+		}
+	}
+}
+
+func (t *Tracer) collect(m SpanModel) {
+	if err := t.collector.Collect(m); err != nil {
+		t.logger.Log("span", m.Name, "err", err)
+	}
+}
+
+func formatID(id uint64) string {
+	s := strconv.FormatUint(id, 16)
+	return strings.Repeat("0", 16-len(s)) + s
+}
+
+func formatTraceID(high, low uint64) string {
+	if high == 0 {
+		return formatID(low)
+	}
+	return formatID(high) + formatID(low)
+}
+
+func parseID(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// parseTraceID accepts both 64 and 128 bit trace IDs.
+func parseTraceID(s string) (high, low uint64, err error) {
+	if len(s) > 16 {
+		if high, err = parseID(s[:len(s)-16]); err != nil {
+			return 0, 0, err
+		}
+		s = s[len(s)-16:]
+	}
+	low, err = parseID(s)
+	return high, low, err
+}
+
+// setIP sets the address of the endpoint, if host is an IP address.
+func setIP(e *Endpoint, host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		e.IPv4 = ip4.String()
+	} else {
+		e.IPv6 = ip.String()
+	}
+	return true
+}
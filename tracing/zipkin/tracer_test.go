@@ -0,0 +1,170 @@
+package zipkin_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	kitot "github.com/go-kit/kit/tracing/opentracing"
+	"github.com/go-kit/kit/tracing/zipkin"
+)
+
+func TestInjectJoin(t *testing.T) {
+	collector := &recordingCollector{}
+	tracer := zipkin.NewTracer(collector, "svc")
+
+	parent := tracer.StartSpan("parent")
+	parent.SetBaggageItem("user", "alice")
+
+	header := http.Header{}
+	if err := tracer.Inject(parent, opentracing.TextMap, opentracing.HTTPHeaderTextMapCarrier(header)); err != nil {
+		t.Fatal(err)
+	}
+	child, err := tracer.Join("child", opentracing.TextMap, opentracing.HTTPHeaderTextMapCarrier(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "alice", child.BaggageItem("user"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	child.Finish()
+	parent.Finish()
+
+	spans := collector.Spans()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	c, p := spans[0], spans[1]
+	if want, have := p.TraceID, c.TraceID; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := p.ID, c.ParentID; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if c.ID == p.ID {
+		t.Errorf("child shares span ID %q with parent", c.ID)
+	}
+	if want, have := "svc", p.LocalEndpoint.ServiceName; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestJoinNotFound(t *testing.T) {
+	tracer := zipkin.NewTracer(&recordingCollector{}, "svc")
+	_, err := tracer.Join("op", opentracing.TextMap, opentracing.HTTPHeaderTextMapCarrier(http.Header{}))
+	if want, have := opentracing.ErrTraceNotFound, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	header := http.Header{}
+	header.Set("X-B3-TraceId", "not-hex")
+	header.Set("X-B3-SpanId", "1")
+	_, err = tracer.Join("op", opentracing.TextMap, opentracing.HTTPHeaderTextMapCarrier(header))
+	if want, have := opentracing.ErrTraceCorrupted, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	collector := &recordingCollector{}
+	tracer := zipkin.NewTracer(collector, "svc", zipkin.TracerSampler(zipkin.NewRateSampler(0)))
+
+	span := tracer.StartSpan("unsampled")
+	header := http.Header{}
+	if err := tracer.Inject(span, opentracing.TextMap, opentracing.HTTPHeaderTextMapCarrier(header)); err != nil {
+		t.Fatal(err)
+	}
+	span.Finish()
+	if want, have := "0", header.Get("X-B3-Sampled"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// The decision of the caller is honored, regardless of the local sampler.
+	header.Set("X-B3-Sampled", "1")
+	joined, err := tracer.Join("sampled", opentracing.TextMap, opentracing.HTTPHeaderTextMapCarrier(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined.Finish()
+
+	spans := collector.Spans()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "sampled", spans[0].Name; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestMiddlewares(t *testing.T) {
+	collector := &recordingCollector{}
+	tracer := zipkin.NewTracer(collector, "svc")
+
+	var e endpoint.Endpoint = func(ctx context.Context, _ interface{}) (interface{}, error) {
+		span := opentracing.SpanFromContext(ctx)
+		span.SetTag("peer.hostname", "10.0.0.1")
+		span.SetTag("peer.port", uint16(8080))
+		span.SetTag("key", 42)
+		span.LogEvent("done")
+		return struct{}{}, nil
+	}
+	e = kitot.TraceClient(tracer, "client")(e)
+	e = kitot.TraceServer(tracer, "server")(e)
+	if _, err := e(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := collector.Spans()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	client, server := spans[0], spans[1]
+	if want, have := "CLIENT", client.Kind; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "SERVER", server.Kind; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := server.ID, client.ParentID; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if client.RemoteEndpoint == nil {
+		t.Fatal("want remote endpoint, have none")
+	}
+	if want, have := (zipkin.Endpoint{IPv4: "10.0.0.1", Port: 8080}), *client.RemoteEndpoint; want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	if want, have := "42", client.Tags["key"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 1, len(client.Annotations); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "done", client.Annotations[0].Value; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+type recordingCollector struct {
+	mtx   sync.Mutex
+	spans []zipkin.SpanModel
+}
+
+func (c *recordingCollector) Collect(m zipkin.SpanModel) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.spans = append(c.spans, m)
+	return nil
+}
+
+func (c *recordingCollector) Close() error { return nil }
+
+func (c *recordingCollector) Spans() []zipkin.SpanModel {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]zipkin.SpanModel{}, c.spans...)
+}
@@ -0,0 +1,111 @@
+package netrpc
+
+import (
+	"fmt"
+	"net/rpc"
+	"reflect"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Client wraps a net/rpc client and provides a method that implements
+// endpoint.Endpoint.
+type Client struct {
+	client        *rpc.Client
+	serviceMethod string
+	enc           EncodeRequestFunc
+	dec           DecodeResponseFunc
+	rpcReply      reflect.Type
+	before        []RequestFunc
+	after         []ResponseFunc
+}
+
+// NewClient constructs a usable Client for a single remote method. The
+// serviceMethod is given in the "Service.Method" form expected by net/rpc.
+// Pass a zero-value of the net/rpc reply type as the rpcReply argument.
+func NewClient(
+	client *rpc.Client,
+	serviceMethod string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	rpcReply interface{},
+	options ...ClientOption,
+) *Client {
+	c := &Client{
+		client:        client,
+		serviceMethod: serviceMethod,
+		enc:           enc,
+		dec:           dec,
+		// We are using reflect.Indirect here to allow both reply structs and
+		// pointers to these reply structs.
+		rpcReply: reflect.TypeOf(
+			reflect.Indirect(
+				reflect.ValueOf(rpcReply),
+			).Interface(),
+		),
+		before: []RequestFunc{},
+		after:  []ResponseFunc{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// ClientBefore sets the RequestFuncs that are applied to the outgoing net/rpc
+// argument object before the call is invoked.
+func ClientBefore(before ...RequestFunc) ClientOption {
+	return func(c *Client) { c.before = before }
+}
+
+// ClientAfter sets the ResponseFuncs that are applied to the incoming net/rpc
+// reply object prior to it being decoded.
+func ClientAfter(after ...ResponseFunc) ClientOption {
+	return func(c *Client) { c.after = after }
+}
+
+// Endpoint returns a usable endpoint that will invoke the net/rpc method
+// specified by the client. If the context is canceled before the call
+// returns, the endpoint returns the context's error; the call itself is left
+// to complete in the background, as net/rpc offers no way to abort it.
+func (c Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		req, err := c.enc(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("Encode: %v", err)
+		}
+
+		for _, f := range c.before {
+			ctx = f(ctx, req)
+		}
+
+		rpcReply := reflect.New(c.rpcReply).Interface()
+		call := c.client.Go(c.serviceMethod, req, rpcReply, make(chan *rpc.Call, 1))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-call.Done:
+		}
+		if call.Error != nil {
+			return nil, fmt.Errorf("Call: %v", call.Error)
+		}
+
+		for _, f := range c.after {
+			ctx = f(ctx, rpcReply)
+		}
+
+		response, err := c.dec(ctx, rpcReply)
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %v", err)
+		}
+		return response, nil
+	}
+}
@@ -0,0 +1,27 @@
+package netrpc
+
+import "golang.org/x/net/context"
+
+// DecodeRequestFunc extracts a user-domain request object from a net/rpc
+// request. It's designed to be used in net/rpc servers, for server-side
+// endpoints. One straightforward DecodeRequestFunc could be something that
+// converts the net/rpc argument type to the concrete request type.
+type DecodeRequestFunc func(context.Context, interface{}) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the net/rpc
+// argument object. It's designed to be used in net/rpc clients, for
+// client-side endpoints. One straightforward EncodeRequestFunc could be
+// something that converts the request object to the net/rpc argument type.
+type EncodeRequestFunc func(context.Context, interface{}) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to the net/rpc reply
+// object. It's designed to be used in net/rpc servers, for server-side
+// endpoints. One straightforward EncodeResponseFunc could be something that
+// converts the response object to the net/rpc reply type.
+type EncodeResponseFunc func(context.Context, interface{}) (response interface{}, err error)
+
+// DecodeResponseFunc extracts a user-domain response object from a net/rpc
+// reply object. It's designed to be used in net/rpc clients, for client-side
+// endpoints. One straightforward DecodeResponseFunc could be something that
+// converts the net/rpc reply type to the concrete response type.
+type DecodeResponseFunc func(context.Context, interface{}) (response interface{}, err error)
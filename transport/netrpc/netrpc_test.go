@@ -0,0 +1,153 @@
+package netrpc_test
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport/netrpc"
+)
+
+type UpperArgs struct{ S string }
+
+type UpperReply struct{ V string }
+
+type Binding struct {
+	upper netrpc.Handler
+}
+
+func (b Binding) Upper(args UpperArgs, reply *UpperReply) error {
+	resp, err := b.upper.ServeNetRPC(args)
+	if err != nil {
+		return err
+	}
+	*reply = resp.(UpperReply)
+	return nil
+}
+
+type contextKey int
+
+const (
+	beforeKey contextKey = iota
+	afterKey
+)
+
+func TestRoundTrip(t *testing.T) {
+	var (
+		serverBefore bool
+		serverAfter  bool
+	)
+	upper := func(ctx context.Context, request interface{}) (interface{}, error) {
+		serverBefore = ctx.Value(beforeKey) != nil
+		return strings.ToUpper(request.(string)), nil
+	}
+	server := netrpc.NewServer(
+		context.Background(),
+		upper,
+		func(_ context.Context, req interface{}) (interface{}, error) { return req.(UpperArgs).S, nil },
+		func(_ context.Context, resp interface{}) (interface{}, error) {
+			return UpperReply{V: resp.(string)}, nil
+		},
+		netrpc.ServerBefore(func(ctx context.Context, _ interface{}) context.Context {
+			return context.WithValue(ctx, beforeKey, true)
+		}),
+		netrpc.ServerAfter(func(ctx context.Context, _ interface{}) context.Context {
+			serverAfter = true
+			return ctx
+		}),
+	)
+	client, stop := newTestClient(t, Binding{upper: server})
+	defer stop()
+
+	var clientAfter bool
+	e := netrpc.NewClient(
+		client,
+		"Binding.Upper",
+		func(_ context.Context, req interface{}) (interface{}, error) { return UpperArgs{S: req.(string)}, nil },
+		func(ctx context.Context, reply interface{}) (interface{}, error) {
+			clientAfter = ctx.Value(afterKey) != nil
+			return reply.(*UpperReply).V, nil
+		},
+		UpperReply{},
+		netrpc.ClientAfter(func(ctx context.Context, _ interface{}) context.Context {
+			return context.WithValue(ctx, afterKey, true)
+		}),
+	).Endpoint()
+
+	response, err := e(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "HELLO", response.(string); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !serverBefore {
+		t.Error("server before func not applied")
+	}
+	if !serverAfter {
+		t.Error("server after func not applied")
+	}
+	if !clientAfter {
+		t.Error("client after func not applied")
+	}
+}
+
+func TestServerBadDecode(t *testing.T) {
+	server := netrpc.NewServer(
+		context.Background(),
+		endpoint.Nop,
+		func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("dang") },
+		func(context.Context, interface{}) (interface{}, error) { return UpperReply{}, nil },
+	)
+	_, err := server.ServeNetRPC(UpperArgs{})
+	if _, ok := err.(netrpc.BadRequestError); !ok {
+		t.Errorf("want BadRequestError, have %v", err)
+	}
+}
+
+func TestClientError(t *testing.T) {
+	server := netrpc.NewServer(
+		context.Background(),
+		func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("dang") },
+		func(_ context.Context, req interface{}) (interface{}, error) { return req, nil },
+		func(context.Context, interface{}) (interface{}, error) { return UpperReply{}, nil },
+	)
+	client, stop := newTestClient(t, Binding{upper: server})
+	defer stop()
+
+	e := netrpc.NewClient(
+		client,
+		"Binding.Upper",
+		func(_ context.Context, req interface{}) (interface{}, error) { return req, nil },
+		func(_ context.Context, reply interface{}) (interface{}, error) { return reply, nil },
+		UpperReply{},
+	).Endpoint()
+
+	_, err := e(context.Background(), UpperArgs{})
+	if err == nil || !strings.Contains(err.Error(), "dang") {
+		t.Errorf("want error containing %q, have %v", "dang", err)
+	}
+}
+
+func newTestClient(t *testing.T, rcvr interface{}) (*rpc.Client, func()) {
+	s := rpc.NewServer()
+	if err := s.Register(rcvr); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Accept(ln)
+	client, err := rpc.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		ln.Close()
+		t.Fatal(err)
+	}
+	return client, func() { client.Close(); ln.Close() }
+}
@@ -0,0 +1,18 @@
+package netrpc
+
+import "golang.org/x/net/context"
+
+// RequestFunc may take information from a net/rpc request and put it into a
+// request context. net/rpc has no notion of request metadata, so RequestFuncs
+// are given the net/rpc argument object itself. In Servers, RequestFuncs are
+// executed prior to decoding the request. In Clients, RequestFuncs are
+// executed after encoding the request but prior to invoking the net/rpc
+// client.
+type RequestFunc func(ctx context.Context, request interface{}) context.Context
+
+// ResponseFunc may take information from a net/rpc reply and put it into a
+// request context. In Servers, ResponseFuncs are executed after invoking the
+// endpoint and encoding the response, but prior to returning the reply. In
+// Clients, ResponseFuncs are executed after the call returns, but prior to
+// decoding the reply.
+type ResponseFunc func(ctx context.Context, response interface{}) context.Context
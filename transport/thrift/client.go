@@ -0,0 +1,125 @@
+package thrift
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// InvokeFunc calls a single method of a generated Thrift client. It's passed
+// the Thrift request produced by the EncodeRequestFunc, and should return the
+// Thrift reply. Generated clients take their arguments individually, so an
+// InvokeFunc is typically a small closure that unpacks the request.
+type InvokeFunc func(request interface{}) (response interface{}, err error)
+
+// Client wraps a generated Thrift client method and provides a method that
+// implements endpoint.Endpoint.
+type Client struct {
+	invoke InvokeFunc
+	enc    EncodeRequestFunc
+	dec    DecodeResponseFunc
+	before []RequestFunc
+	after  []ResponseFunc
+	in     *HeaderProtocol
+	out    *HeaderProtocol
+}
+
+// NewClient constructs a usable Client for a single remote method. Generated
+// Thrift clients are not safe for concurrent use, and neither is the
+// resulting endpoint; callers are responsible for serializing calls, or for
+// using one client per goroutine.
+func NewClient(
+	invoke InvokeFunc,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	options ...ClientOption,
+) *Client {
+	c := &Client{
+		invoke: invoke,
+		enc:    enc,
+		dec:    dec,
+		before: []RequestFunc{},
+		after:  []ResponseFunc{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// ClientBefore sets the RequestFuncs that are applied to the outgoing Thrift
+// request header before the call is invoked.
+func ClientBefore(before ...RequestFunc) ClientOption {
+	return func(c *Client) { c.before = before }
+}
+
+// ClientAfter sets the ResponseFuncs that are applied to the incoming Thrift
+// reply header prior to the reply being decoded.
+func ClientAfter(after ...ResponseFunc) ClientOption {
+	return func(c *Client) { c.after = after }
+}
+
+// ClientProtocols sets the input and output protocols of the generated Thrift
+// client, which allows the Client to send and receive headers. Headers are
+// only exchanged if the protocols are HeaderProtocols, e.g. if they were
+// produced by a factory returned by NewHeaderProtocolFactory.
+func ClientProtocols(in, out thrift.TProtocol) ClientOption {
+	return func(c *Client) {
+		c.in, _ = in.(*HeaderProtocol)
+		c.out, _ = out.(*HeaderProtocol)
+	}
+}
+
+// Endpoint returns a usable endpoint that invokes the remote method. If the
+// context has a deadline, it is sent to the server in the request header.
+// Generated Thrift clients can't be interrupted, so the deadline is not
+// enforced locally. Errors returned by the Thrift client, including
+// exceptions declared in the IDL, are returned unchanged.
+func (c Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		req, err := c.enc(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("Encode: %v", err)
+		}
+
+		reqHeader := Header{}
+		if deadline, ok := ctx.Deadline(); ok {
+			reqHeader.Set(DeadlineHeader, deadline.UTC().Format(time.RFC3339Nano))
+		}
+		for _, f := range c.before {
+			ctx = f(ctx, reqHeader)
+		}
+		if c.out != nil {
+			c.out.SetWriteHeader(reqHeader)
+		}
+
+		reply, err := c.invoke(req)
+		if err != nil {
+			return nil, err
+		}
+
+		respHeader := Header{}
+		if c.in != nil {
+			respHeader = c.in.ReadHeader()
+		}
+		for _, f := range c.after {
+			ctx = f(ctx, respHeader)
+		}
+
+		response, err := c.dec(ctx, reply)
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %v", err)
+		}
+		return response, nil
+	}
+}
@@ -0,0 +1,127 @@
+package thrift_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"golang.org/x/net/context"
+
+	kitthrift "github.com/go-kit/kit/transport/thrift"
+)
+
+func TestClientHeaders(t *testing.T) {
+	var (
+		reqBuf    = thrift.NewTMemoryBuffer()
+		replyBuf  = thrift.NewTMemoryBuffer()
+		clientOut = kitthrift.NewHeaderProtocol(thrift.NewTBinaryProtocolTransport(reqBuf))
+		serverIn  = kitthrift.NewHeaderProtocol(thrift.NewTBinaryProtocolTransport(reqBuf))
+		serverOut = kitthrift.NewHeaderProtocol(thrift.NewTBinaryProtocolTransport(replyBuf))
+		clientIn  = kitthrift.NewHeaderProtocol(thrift.NewTBinaryProtocolTransport(replyBuf))
+		deadline  = time.Now().Add(time.Minute).UTC()
+		reqHeader kitthrift.Header
+	)
+
+	// The invoke func plays both the generated client and the server.
+	invoke := func(request interface{}) (interface{}, error) {
+		if err := clientOut.WriteMessageBegin("Echo", thrift.CALL, 1); err != nil {
+			return nil, err
+		}
+		if _, _, _, err := serverIn.ReadMessageBegin(); err != nil {
+			return nil, err
+		}
+		reqHeader = serverIn.ReadHeader()
+
+		serverOut.SetWriteHeader(kitthrift.Header{"trace-id": "xyz"})
+		if err := serverOut.WriteMessageBegin("Echo", thrift.REPLY, 1); err != nil {
+			return nil, err
+		}
+		if _, _, _, err := clientIn.ReadMessageBegin(); err != nil {
+			return nil, err
+		}
+		return request, nil
+	}
+
+	type traceKey struct{}
+	var traceID interface{}
+	nop := func(_ context.Context, x interface{}) (interface{}, error) { return x, nil }
+	decode := func(ctx context.Context, x interface{}) (interface{}, error) {
+		traceID = ctx.Value(traceKey{})
+		return x, nil
+	}
+	client := kitthrift.NewClient(
+		invoke,
+		nop,
+		decode,
+		kitthrift.ClientProtocols(clientIn, clientOut),
+		kitthrift.ClientBefore(kitthrift.SetRequestHeader("span-id", "abc")),
+		kitthrift.ClientAfter(func(ctx context.Context, h kitthrift.Header) context.Context {
+			return context.WithValue(ctx, traceKey{}, h["trace-id"])
+		}),
+	)
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	response, err := client.Endpoint()(ctx, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "hello", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "abc", reqHeader["span-id"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := deadline.Format(time.RFC3339Nano), reqHeader[kitthrift.DeadlineHeader]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "xyz", traceID; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestClientWithoutHeaders(t *testing.T) {
+	var (
+		buf = thrift.NewTMemoryBuffer()
+		out = thrift.NewTBinaryProtocolTransport(buf)
+		in  = thrift.NewTBinaryProtocolTransport(buf)
+	)
+
+	// Plain protocols stay plain, so the client can talk to any Thrift peer.
+	invoke := func(request interface{}) (interface{}, error) {
+		return request, out.WriteMessageBegin("Echo", thrift.CALL, 1)
+	}
+	var afterCalled bool
+	nop := func(_ context.Context, x interface{}) (interface{}, error) { return x, nil }
+	client := kitthrift.NewClient(
+		invoke,
+		nop,
+		nop,
+		kitthrift.ClientProtocols(in, out),
+		kitthrift.ClientBefore(kitthrift.SetRequestHeader("span-id", "abc")),
+		kitthrift.ClientAfter(func(ctx context.Context, h kitthrift.Header) context.Context {
+			afterCalled = true
+			if want, have := 0, len(h); want != have {
+				t.Errorf("want %d, have %d", want, have)
+			}
+			return ctx
+		}),
+	)
+	if _, err := client.Endpoint()(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if !afterCalled {
+		t.Error("want ClientAfter called, have not")
+	}
+
+	name, _, seqID, err := in.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "Echo", name; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := int32(1), seqID; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,27 @@
+package thrift
+
+import "golang.org/x/net/context"
+
+// DecodeRequestFunc extracts a user-domain request object from a Thrift
+// request. It's designed to be used in Thrift servers, for server-side
+// endpoints. One straightforward DecodeRequestFunc could be something that
+// converts the generated Thrift argument struct to the concrete request type.
+type DecodeRequestFunc func(context.Context, interface{}) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the Thrift request
+// object. It's designed to be used in Thrift clients, for client-side
+// endpoints. One straightforward EncodeRequestFunc could be something that
+// converts the request object to the generated Thrift argument struct.
+type EncodeRequestFunc func(context.Context, interface{}) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to the Thrift reply
+// object. It's designed to be used in Thrift servers, for server-side
+// endpoints. One straightforward EncodeResponseFunc could be something that
+// converts the response object to the generated Thrift reply struct.
+type EncodeResponseFunc func(context.Context, interface{}) (response interface{}, err error)
+
+// DecodeResponseFunc extracts a user-domain response object from a Thrift
+// reply object. It's designed to be used in Thrift clients, for client-side
+// endpoints. One straightforward DecodeResponseFunc could be something that
+// converts the generated Thrift reply struct to the concrete response type.
+type DecodeResponseFunc func(context.Context, interface{}) (response interface{}, err error)
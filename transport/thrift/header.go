@@ -0,0 +1,173 @@
+package thrift
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"golang.org/x/net/context"
+)
+
+// DeadlineHeader is the header key used to propagate the deadline of a
+// request context from clients to servers. The value is formatted according
+// to RFC 3339, with nanoseconds.
+const DeadlineHeader = "kit-deadline"
+
+// Header is a set of key-value pairs transmitted alongside a Thrift message,
+// used to carry request-scoped metadata such as trace identifiers and
+// deadlines. It implements the opentracing TextMapReader and TextMapWriter
+// interfaces, so it may be used directly as a span propagation carrier.
+type Header map[string]string
+
+// Set sets the value for key, replacing any existing value.
+func (h Header) Set(key, val string) {
+	h[key] = val
+}
+
+// ForeachKey calls handler for each key-value pair, stopping at the first
+// error, which is returned.
+func (h Header) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range h {
+		if err := handler(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h Header) deadline() (time.Time, bool) {
+	s, ok := h[DeadlineHeader]
+	if !ok {
+		return time.Time{}, false
+	}
+	d, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d, true
+}
+
+// HeaderProtocol wraps a Thrift protocol, and prefixes every message it writes
+// with a Header, encoded as a Thrift map of strings. Conversely, it expects a
+// Header before every message it reads. Thrift has no native support for
+// message headers, so clients and servers must both use a HeaderProtocol, or
+// neither; a HeaderProtocol cannot talk to a peer using the plain protocol.
+type HeaderProtocol struct {
+	thrift.TProtocol
+
+	mtx   sync.Mutex
+	read  Header
+	write Header
+}
+
+// NewHeaderProtocol wraps the passed protocol.
+func NewHeaderProtocol(p thrift.TProtocol) *HeaderProtocol {
+	return &HeaderProtocol{
+		TProtocol: p,
+		read:      Header{},
+	}
+}
+
+// NewHeaderProtocolFactory returns a protocol factory that wraps each
+// protocol produced by the passed factory in a HeaderProtocol.
+func NewHeaderProtocolFactory(f thrift.TProtocolFactory) thrift.TProtocolFactory {
+	return headerProtocolFactory{f}
+}
+
+type headerProtocolFactory struct {
+	thrift.TProtocolFactory
+}
+
+func (f headerProtocolFactory) GetProtocol(t thrift.TTransport) thrift.TProtocol {
+	return NewHeaderProtocol(f.TProtocolFactory.GetProtocol(t))
+}
+
+// ReadHeader returns the header of the message most recently read.
+func (p *HeaderProtocol) ReadHeader() Header {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.read
+}
+
+// SetWriteHeader sets the header to send with the next message written. It
+// applies to a single message only; subsequent messages are sent with an
+// empty header unless SetWriteHeader is called again.
+func (p *HeaderProtocol) SetWriteHeader(h Header) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.write = h
+}
+
+// WriteMessageBegin writes the pending header, and then the message header.
+func (p *HeaderProtocol) WriteMessageBegin(name string, typeID thrift.TMessageType, seqID int32) error {
+	p.mtx.Lock()
+	h := p.write
+	p.write = nil
+	p.mtx.Unlock()
+
+	if err := p.TProtocol.WriteMapBegin(thrift.STRING, thrift.STRING, len(h)); err != nil {
+		return err
+	}
+	for k, v := range h {
+		if err := p.TProtocol.WriteString(k); err != nil {
+			return err
+		}
+		if err := p.TProtocol.WriteString(v); err != nil {
+			return err
+		}
+	}
+	if err := p.TProtocol.WriteMapEnd(); err != nil {
+		return err
+	}
+	return p.TProtocol.WriteMessageBegin(name, typeID, seqID)
+}
+
+// ReadMessageBegin reads the header, and then the message header.
+func (p *HeaderProtocol) ReadMessageBegin() (name string, typeID thrift.TMessageType, seqID int32, err error) {
+	_, _, size, err := p.TProtocol.ReadMapBegin()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	h := make(Header, size)
+	for i := 0; i < size; i++ {
+		k, err := p.TProtocol.ReadString()
+		if err != nil {
+			return "", 0, 0, err
+		}
+		v, err := p.TProtocol.ReadString()
+		if err != nil {
+			return "", 0, 0, err
+		}
+		h[k] = v
+	}
+	if err := p.TProtocol.ReadMapEnd(); err != nil {
+		return "", 0, 0, err
+	}
+
+	p.mtx.Lock()
+	p.read = h
+	p.mtx.Unlock()
+
+	return p.TProtocol.ReadMessageBegin()
+}
+
+type contextKey int
+
+const (
+	requestHeaderKey contextKey = iota
+	responseHeaderKey
+)
+
+func requestHeader(ctx context.Context) Header {
+	if h, ok := ctx.Value(requestHeaderKey).(Header); ok {
+		return h
+	}
+	return Header{}
+}
+
+func responseHeader(ctx context.Context) Header {
+	if h, ok := ctx.Value(responseHeaderKey).(Header); ok {
+		return h
+	}
+	return Header{}
+}
@@ -0,0 +1,90 @@
+package thrift_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"golang.org/x/net/context"
+
+	kitthrift "github.com/go-kit/kit/transport/thrift"
+)
+
+func TestHeaderProtocolRoundTrip(t *testing.T) {
+	buf := thrift.NewTMemoryBuffer()
+	out := kitthrift.NewHeaderProtocol(thrift.NewTBinaryProtocolTransport(buf))
+	in := kitthrift.NewHeaderProtocol(thrift.NewTBinaryProtocolTransport(buf))
+
+	out.SetWriteHeader(kitthrift.Header{"trace-id": "abc"})
+	if err := out.WriteMessageBegin("Sum", thrift.CALL, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.WriteMessageBegin("Sum", thrift.CALL, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	name, _, seqID, err := in.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "Sum", name; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := int32(1), seqID; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "abc", in.ReadHeader()["trace-id"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// The header applies to a single message only.
+	if _, _, _, err := in.ReadMessageBegin(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 0, len(in.ReadHeader()); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestServerDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Minute).UTC()
+
+	buf := thrift.NewTMemoryBuffer()
+	out := kitthrift.NewHeaderProtocol(thrift.NewTBinaryProtocolTransport(buf))
+	in := kitthrift.NewHeaderProtocol(thrift.NewTBinaryProtocolTransport(buf))
+
+	out.SetWriteHeader(kitthrift.Header{kitthrift.DeadlineHeader: deadline.Format(time.RFC3339Nano)})
+	if err := out.WriteMessageBegin("Echo", thrift.CALL, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var have time.Time
+	e := func(ctx context.Context, request interface{}) (interface{}, error) {
+		have, _ = ctx.Deadline()
+		return request, nil
+	}
+	nop := func(_ context.Context, x interface{}) (interface{}, error) { return x, nil }
+	server := kitthrift.NewServer(context.Background(), e, nop, nop)
+
+	factory := kitthrift.NewProcessorFactory(func(conn *kitthrift.Conn) thrift.TProcessor {
+		return processorFunc(func(in, out thrift.TProtocol) (bool, thrift.TException) {
+			if _, _, _, err := in.ReadMessageBegin(); err != nil {
+				return false, err
+			}
+			_, err := server.ServeThrift(conn.Context(context.Background()), "hello")
+			return err == nil, err
+		})
+	})
+	if _, err := factory.GetProcessor(buf).Process(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if !have.Equal(deadline) {
+		t.Errorf("want %v, have %v", deadline, have)
+	}
+}
+
+type processorFunc func(in, out thrift.TProtocol) (bool, thrift.TException)
+
+func (f processorFunc) Process(in, out thrift.TProtocol) (bool, thrift.TException) {
+	return f(in, out)
+}
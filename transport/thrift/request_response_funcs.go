@@ -0,0 +1,35 @@
+package thrift
+
+import "golang.org/x/net/context"
+
+// RequestFunc may take information from a Thrift request header and put it
+// into a request context. In Servers, RequestFuncs are executed prior to
+// invoking the endpoint. In Clients, RequestFuncs are executed after encoding
+// the request but prior to invoking the Thrift client, and may add entries to
+// the header sent with the request.
+type RequestFunc func(context.Context, Header) context.Context
+
+// ResponseFunc may take information from a request context and use it to
+// manipulate a Thrift reply header. In Servers, ResponseFuncs are executed
+// after invoking the endpoint but prior to encoding the reply. In Clients,
+// ResponseFuncs are executed after the call returns, and may take information
+// from the reply header and put it into the context, prior to decoding.
+type ResponseFunc func(context.Context, Header) context.Context
+
+// SetRequestHeader returns a RequestFunc that sets the specified header
+// key-value pair.
+func SetRequestHeader(key, val string) RequestFunc {
+	return func(ctx context.Context, h Header) context.Context {
+		h.Set(key, val)
+		return ctx
+	}
+}
+
+// SetResponseHeader returns a ResponseFunc that sets the specified header
+// key-value pair.
+func SetResponseHeader(key, val string) ResponseFunc {
+	return func(ctx context.Context, h Header) context.Context {
+		h.Set(key, val)
+		return ctx
+	}
+}
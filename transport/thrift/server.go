@@ -0,0 +1,190 @@
+package thrift
+
+import (
+	"sync"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Handler which should be called from the Thrift binding of the service
+// implementation. The incoming request parameter, and returned response
+// parameter, are both Thrift types, not user-domain.
+type Handler interface {
+	ServeThrift(ctx context.Context, request interface{}) (response interface{}, err error)
+}
+
+// Server wraps an endpoint and implements thrift.Handler.
+type Server struct {
+	ctx    context.Context
+	e      endpoint.Endpoint
+	dec    DecodeRequestFunc
+	enc    EncodeResponseFunc
+	before []RequestFunc
+	after  []ResponseFunc
+	logger log.Logger
+}
+
+// NewServer constructs a new server, which implements Handler and wraps the
+// provided endpoint. Consumers should write bindings that adapt the methods of
+// their generated Thrift service interface to individual handlers. Request and
+// response objects are from the caller business domain, not Thrift types.
+func NewServer(
+	ctx context.Context,
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		ctx:    ctx,
+		e:      e,
+		dec:    dec,
+		enc:    enc,
+		logger: log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on the Thrift request header before the
+// request is decoded.
+func ServerBefore(before ...RequestFunc) ServerOption {
+	return func(s *Server) { s.before = before }
+}
+
+// ServerAfter functions are executed on the Thrift reply header after the
+// endpoint is invoked, but before the reply is encoded.
+func ServerAfter(after ...ResponseFunc) ServerOption {
+	return func(s *Server) { s.after = after }
+}
+
+// ServerErrorLogger is used to log non-terminal errors. By default, no errors
+// are logged.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// ServeThrift implements the Handler interface. The passed context should be
+// obtained from Conn.Context, so that request and reply headers are available
+// to the server; any other context is treated as carrying empty headers. If
+// the request header carries a deadline, it is applied to the context passed
+// to the endpoint.
+func (s Server) ServeThrift(thriftCtx context.Context, req interface{}) (interface{}, error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	reqHeader := requestHeader(thriftCtx)
+	if deadline, ok := reqHeader.deadline(); ok {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+		defer cancelDeadline()
+	}
+
+	for _, f := range s.before {
+		ctx = f(ctx, reqHeader)
+	}
+
+	request, err := s.dec(ctx, req)
+	if err != nil {
+		s.logger.Log("err", err)
+		return nil, BadRequestError{err}
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.logger.Log("err", err)
+		return nil, err
+	}
+
+	respHeader := responseHeader(thriftCtx)
+	for _, f := range s.after {
+		ctx = f(ctx, respHeader)
+	}
+
+	thriftResp, err := s.enc(ctx, response)
+	if err != nil {
+		s.logger.Log("err", err)
+		return nil, err
+	}
+
+	return thriftResp, nil
+}
+
+// BadRequestError is an error in decoding the request.
+type BadRequestError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (err BadRequestError) Error() string {
+	return err.Err.Error()
+}
+
+// Conn gives the Thrift handler of a single connection access to the headers
+// of the message currently being processed. Generated Thrift handlers are not
+// passed a context, so a handler that wants to use headers must be constructed
+// per connection, via NewProcessorFactory.
+type Conn struct {
+	mtx sync.Mutex
+	in  *HeaderProtocol
+	out *HeaderProtocol
+}
+
+// Context returns a child of ctx carrying the header of the request being
+// processed, and the header that will be sent with its reply. It should be
+// called from the generated handler method, and the result passed on to
+// ServeThrift. If the connection doesn't use a HeaderProtocol, both headers
+// are empty, and the reply header is discarded.
+func (c *Conn) Context(ctx context.Context) context.Context {
+	reqHeader, respHeader := Header{}, Header{}
+
+	c.mtx.Lock()
+	if c.in != nil {
+		reqHeader = c.in.ReadHeader()
+	}
+	if c.out != nil {
+		c.out.SetWriteHeader(respHeader)
+	}
+	c.mtx.Unlock()
+
+	ctx = context.WithValue(ctx, requestHeaderKey, reqHeader)
+	return context.WithValue(ctx, responseHeaderKey, respHeader)
+}
+
+// NewProcessorFactory returns a Thrift processor factory that invokes
+// newProcessor for each accepted connection, passing a Conn bound to that
+// connection. Use it with a Thrift server constructor that accepts a
+// processor factory, e.g. thrift.NewTSimpleServerFactory4, together with a
+// protocol factory from NewHeaderProtocolFactory.
+func NewProcessorFactory(newProcessor func(*Conn) thrift.TProcessor) thrift.TProcessorFactory {
+	return processorFactory(newProcessor)
+}
+
+type processorFactory func(*Conn) thrift.TProcessor
+
+func (f processorFactory) GetProcessor(thrift.TTransport) thrift.TProcessor {
+	c := &Conn{}
+	return &connProcessor{conn: c, next: f(c)}
+}
+
+type connProcessor struct {
+	conn *Conn
+	next thrift.TProcessor
+}
+
+func (p *connProcessor) Process(in, out thrift.TProtocol) (bool, thrift.TException) {
+	p.conn.mtx.Lock()
+	p.conn.in, _ = in.(*HeaderProtocol)
+	p.conn.out, _ = out.(*HeaderProtocol)
+	p.conn.mtx.Unlock()
+	return p.next.Process(in, out)
+}